@@ -3,12 +3,15 @@ package main
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"image"
 	"image/jpeg"
 	"log"
 	"sync"
 
 	"github.com/blackjack/webcam"
+	"github.com/lsnow99/cam-server/config"
+	"github.com/lsnow99/cam-server/framehub"
 )
 
 /*
@@ -19,6 +22,7 @@ import (
 var (
 	V4L2_PIX_FMT_PJPG  webcam.PixelFormat
 	V4L2_PIX_FMT_MJPEG webcam.PixelFormat
+	V4L2_PIX_FMT_YUYV  webcam.PixelFormat
 )
 
 func fourCCToU32(b []byte) uint32 {
@@ -59,26 +63,80 @@ func initFormatCodes() {
 		3: 'G',
 	}
 	V4L2_PIX_FMT_MJPEG = webcam.PixelFormat(fourCCToU32(mjpeg))
+	yuyv := []byte{
+		0: 'Y',
+		1: 'U',
+		2: 'Y',
+		3: 'V',
+	}
+	V4L2_PIX_FMT_YUYV = webcam.PixelFormat(fourCCToU32(yuyv))
+}
+
+// namedPixelFormats maps the pixel format names accepted in a camera's
+// config.Camera.PixelFormats preference list to their V4L2 fourCC codes.
+// initFormatCodes must run before this is read.
+func namedPixelFormats() map[string]webcam.PixelFormat {
+	return map[string]webcam.PixelFormat{
+		"MJPEG": V4L2_PIX_FMT_MJPEG,
+		"PJPG":  V4L2_PIX_FMT_PJPG,
+		"YUYV":  V4L2_PIX_FMT_YUYV,
+	}
 }
 
-// StreamWorker main loop to grab raw frame data from the camera
-func StreamWorker(ctx context.Context, errCh chan error, wg *sync.WaitGroup, li chan *bytes.Buffer) {
+// selectPixelFormat picks the first format in prefs that cam actually
+// supports, so the same config works across devices with different
+// capabilities (e.g. a UVC webcam offering MJPEG vs. a cheaper one that
+// only does YUYV).
+func selectPixelFormat(cam *webcam.Webcam, prefs []string) (webcam.PixelFormat, error) {
+	supported := cam.GetSupportedFormats()
+	named := namedPixelFormats()
+
+	for _, name := range prefs {
+		fcc, ok := named[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown pixel format %q", name)
+		}
+		if _, ok := supported[fcc]; ok {
+			return fcc, nil
+		}
+	}
+
+	return 0, fmt.Errorf("camera does not support any of the requested pixel formats %v", prefs)
+}
+
+// StreamWorker main loop to grab raw frame data from the camera described
+// by cfg
+func StreamWorker(ctx context.Context, errCh chan error, wg *sync.WaitGroup, hub *framehub.FrameHub, cfg config.Camera) {
 	// This is guaranteed to run as the last thing before this function returns
 	defer wg.Done()
 
-	log.Println("Starting camera streaming worker")
+	log.Printf("Starting camera streaming worker for %q (%s)", cfg.Name, cfg.Device)
 
 	initFormatCodes()
 
-	cam, err := webcam.Open("/dev/video0") // Open webcam
+	cam, err := webcam.Open(cfg.Device)
 	if err != nil {
 		errCh <- err
+		return
 	}
 	defer cam.Close()
 
+	fcc, err := selectPixelFormat(cam, cfg.PixelFormats)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	_, w, h, err := cam.SetImageFormat(fcc, cfg.Width, cfg.Height)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
 	err = cam.StartStreaming()
 	if err != nil {
 		errCh <- err
+		return
 	}
 
 	doShutdown := false
@@ -86,7 +144,7 @@ func StreamWorker(ctx context.Context, errCh chan error, wg *sync.WaitGroup, li
 	// Register our handler to process shutdown requests
 	go func() {
 		<-ctx.Done()
-		log.Println("Gracefully shutting down camera streamer")
+		log.Printf("Gracefully shutting down camera streamer for %q", cfg.Name)
 		cam.StopStreaming()
 		doShutdown = true
 	}()
@@ -97,7 +155,7 @@ func StreamWorker(ctx context.Context, errCh chan error, wg *sync.WaitGroup, li
 		back chan struct{} = make(chan struct{})
 	)
 
-	go encodeToImage(cam, back, fi, li, 1920, 1080, errCh)
+	go encodeToImage(cam, back, fi, hub, w, h, errCh)
 
 	failures := 0
 	for !doShutdown {
@@ -128,19 +186,18 @@ func StreamWorker(ctx context.Context, errCh chan error, wg *sync.WaitGroup, li
 		}
 	}
 
-	log.Println("Stopped camera streamer")
+	log.Printf("Stopped camera streamer for %q", cfg.Name)
 }
 
 /*
-	encodeToImage This function is supposed to take the raw bytes output by the camera streamer on the fi channel and convert 
-	them to jpeg, and then forward the jpeg bytes onto the li channel. It turns out the bytes we are getting are already jpeg 
+	encodeToImage This function is supposed to take the raw bytes output by the camera streamer on the fi channel and convert
+	them to jpeg, and then publish the jpeg bytes to the frame hub. It turns out the bytes we are getting are already jpeg
 	so most of this should can probably be removed (TODO: verify this). When a new frame is pushed to fi, it is picked up by
-	this function and processed. The processed jpeg is pushed onto li, and up to N client goroutines can grab the frame from
-	li before a new frame is grabbed from fi. At least one client goroutine must draw a frame from li before the loop
-	continues and a fresh frame is grabbed. Consequently, it is advised to pop a frame off of li before grabbing a fresh one
-	in a client goroutine if it does not know how recently the last frame was requested.
+	this function and processed. The processed jpeg is published to hub, which fans it out to every current subscriber
+	(HandleStream, HandleSnapshot, TimelapseWorker, ...) independently, so a subscriber that falls behind no longer holds
+	up this loop or any other subscriber.
 */
-func encodeToImage(wc *webcam.Webcam, back chan struct{}, fi chan []byte, li chan *bytes.Buffer, w, h uint32, errCh chan error) {
+func encodeToImage(wc *webcam.Webcam, back chan struct{}, fi chan []byte, hub *framehub.FrameHub, w, h uint32, errCh chan error) {
 
 	var (
 		frame []byte
@@ -167,35 +224,9 @@ func encodeToImage(wc *webcam.Webcam, back chan struct{}, fi chan []byte, li cha
 			return
 		}
 
-		const N = 50
-		// broadcast image up to N ready clients
-		nn := 0
-	FOR:
-		for ; nn < N; nn++ {
-			/*
-				This select statement will check if anyone is waiting on data to come in through `li`, and if so, it will
-				send the data to that client. If no one is currently waiting, then the default case runs, and the for loop
-				breaks. The purpose of this is so that we can have N goroutine clients concurrently receive the same frame
-				allowing for concurrent access to the stream. (Otherwise, each client goroutine would receive unique
-				frames, which could quickly slow down their streams)
-			*/
-			select {
-			case li <- buf:
-			default:
-				break FOR
-			}
-		}
-
-		/*
-			If the number of clients we successfully broadcast the frame to was zero, this means no goroutine is currently
-			waiting on a frame. Instead of grabbing more frames to process that no one wants, we will wait here for the
-			next goroutine to request a frame. NOTE: this implementation has the side effect that the next frame will be
-			stale if significant time passes before a goroutine requests a frame. Therefore, it is advised to perform a
-			`<-li` in the client goroutine before requesting another frame to clear the stale one.
-		*/
-		if nn == 0 {
-			li <- buf
-		}
-
+		// Publish broadcasts buf to every current subscriber without blocking,
+		// dropping a slow subscriber's oldest buffered frame if it has fallen
+		// behind rather than stalling this loop.
+		hub.Publish(buf)
 	}
 }
@@ -0,0 +1,106 @@
+// Hand-written message types for the CamService API defined in
+// camrpc/camrpc.proto, wire-compatible with protoc-gen-go output but using
+// the older github.com/golang/protobuf v1 API (no file descriptor,
+// proto.RegisterType only) rather than the v2 protoimpl/ProtoReflect
+// machinery, so they don't support reflection-based tools like grpcurl.
+// These are maintained by hand against the .proto, not generated by protoc;
+// keep them in sync with camrpc.proto when it changes.
+
+package camrpc
+
+import (
+	proto "github.com/golang/protobuf/proto"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+
+// StreamRequest is the request message for CamService.StreamFrames.
+type StreamRequest struct {
+	// max_fps caps the rate frames are delivered to the client. 0 means
+	// deliver every published frame with no throttling.
+	MaxFps float64 `protobuf:"fixed64,1,opt,name=max_fps,json=maxFps,proto3" json:"max_fps,omitempty"`
+	// only_on_change, when true, skips delivering a frame whose sequence
+	// number matches the last one sent to this client (no-op for the hub
+	// today, reserved for when motion-aware encoders may publish unchanged
+	// frames less often).
+	OnlyOnChange bool `protobuf:"varint,2,opt,name=only_on_change,json=onlyOnChange,proto3" json:"only_on_change,omitempty"`
+	// since_seq, when non-zero, asks the server to skip delivering frames
+	// with seq <= since_seq, letting a reconnecting client resume from where
+	// it left off instead of re-receiving frames it already has.
+	SinceSeq uint32 `protobuf:"varint,3,opt,name=since_seq,json=sinceSeq,proto3" json:"since_seq,omitempty"`
+}
+
+func (m *StreamRequest) Reset()         { *m = StreamRequest{} }
+func (m *StreamRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamRequest) ProtoMessage()    {}
+
+func (m *StreamRequest) GetMaxFps() float64 {
+	if m != nil {
+		return m.MaxFps
+	}
+	return 0
+}
+
+func (m *StreamRequest) GetOnlyOnChange() bool {
+	if m != nil {
+		return m.OnlyOnChange
+	}
+	return false
+}
+
+func (m *StreamRequest) GetSinceSeq() uint32 {
+	if m != nil {
+		return m.SinceSeq
+	}
+	return 0
+}
+
+// SnapshotRequest is the request message for CamService.Snapshot.
+type SnapshotRequest struct{}
+
+func (m *SnapshotRequest) Reset()         { *m = SnapshotRequest{} }
+func (m *SnapshotRequest) String() string { return proto.CompactTextString(m) }
+func (*SnapshotRequest) ProtoMessage()    {}
+
+// Frame carries a single encoded camera frame.
+type Frame struct {
+	// jpeg is the JPEG-encoded frame data.
+	Jpeg []byte `protobuf:"bytes,1,opt,name=jpeg,proto3" json:"jpeg,omitempty"`
+	// unix_nanos is the time the frame was published to the hub.
+	UnixNanos int64 `protobuf:"varint,2,opt,name=unix_nanos,json=unixNanos,proto3" json:"unix_nanos,omitempty"`
+	// seq is a monotonically increasing sequence number assigned per frame,
+	// used by clients to detect gaps and by since_seq to resume a stream.
+	Seq uint32 `protobuf:"varint,3,opt,name=seq,proto3" json:"seq,omitempty"`
+}
+
+func (m *Frame) Reset()         { *m = Frame{} }
+func (m *Frame) String() string { return proto.CompactTextString(m) }
+func (*Frame) ProtoMessage()    {}
+
+func (m *Frame) GetJpeg() []byte {
+	if m != nil {
+		return m.Jpeg
+	}
+	return nil
+}
+
+func (m *Frame) GetUnixNanos() int64 {
+	if m != nil {
+		return m.UnixNanos
+	}
+	return 0
+}
+
+func (m *Frame) GetSeq() uint32 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*StreamRequest)(nil), "camrpc.StreamRequest")
+	proto.RegisterType((*SnapshotRequest)(nil), "camrpc.SnapshotRequest")
+	proto.RegisterType((*Frame)(nil), "camrpc.Frame")
+}
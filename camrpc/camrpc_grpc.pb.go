@@ -0,0 +1,160 @@
+// Hand-written client/server stubs for the CamService API defined in
+// camrpc/camrpc.proto, matching the shape protoc-gen-go-grpc would emit.
+// These are maintained by hand against the .proto rather than generated by
+// protoc; keep them in sync with camrpc.proto when it changes.
+
+package camrpc
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// CamServiceClient is the client API for CamService service.
+type CamServiceClient interface {
+	// StreamFrames streams frames to the client as they are published to the
+	// frame hub, optionally throttled and/or deduplicated per StreamRequest.
+	StreamFrames(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (CamService_StreamFramesClient, error)
+	// Snapshot returns the single most recently published frame.
+	Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Frame, error)
+}
+
+type camServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewCamServiceClient returns a CamServiceClient backed by cc.
+func NewCamServiceClient(cc grpc.ClientConnInterface) CamServiceClient {
+	return &camServiceClient{cc}
+}
+
+func (c *camServiceClient) StreamFrames(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (CamService_StreamFramesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_CamService_serviceDesc.Streams[0], "/camrpc.CamService/StreamFrames", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &camServiceStreamFramesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// CamService_StreamFramesClient is the client-side stream returned by
+// StreamFrames.
+type CamService_StreamFramesClient interface {
+	Recv() (*Frame, error)
+	grpc.ClientStream
+}
+
+type camServiceStreamFramesClient struct {
+	grpc.ClientStream
+}
+
+func (x *camServiceStreamFramesClient) Recv() (*Frame, error) {
+	m := new(Frame)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *camServiceClient) Snapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*Frame, error) {
+	out := new(Frame)
+	err := c.cc.Invoke(ctx, "/camrpc.CamService/Snapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CamServiceServer is the server API for CamService service. Implementations
+// must embed UnimplementedCamServiceServer for forward compatibility.
+type CamServiceServer interface {
+	StreamFrames(*StreamRequest, CamService_StreamFramesServer) error
+	Snapshot(context.Context, *SnapshotRequest) (*Frame, error)
+}
+
+// UnimplementedCamServiceServer can be embedded to have forward compatible
+// implementations.
+type UnimplementedCamServiceServer struct{}
+
+func (UnimplementedCamServiceServer) StreamFrames(*StreamRequest, CamService_StreamFramesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamFrames not implemented")
+}
+
+func (UnimplementedCamServiceServer) Snapshot(context.Context, *SnapshotRequest) (*Frame, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Snapshot not implemented")
+}
+
+// RegisterCamServiceServer registers srv with s under the CamService
+// service name.
+func RegisterCamServiceServer(s *grpc.Server, srv CamServiceServer) {
+	s.RegisterService(&_CamService_serviceDesc, srv)
+}
+
+func _CamService_StreamFrames_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CamServiceServer).StreamFrames(m, &camServiceStreamFramesServer{stream})
+}
+
+// CamService_StreamFramesServer is the server-side stream passed to
+// StreamFrames implementations.
+type CamService_StreamFramesServer interface {
+	Send(*Frame) error
+	grpc.ServerStream
+}
+
+type camServiceStreamFramesServer struct {
+	grpc.ServerStream
+}
+
+func (x *camServiceStreamFramesServer) Send(m *Frame) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CamService_Snapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CamServiceServer).Snapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/camrpc.CamService/Snapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CamServiceServer).Snapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _CamService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "camrpc.CamService",
+	HandlerType: (*CamServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Snapshot",
+			Handler:    _CamService_Snapshot_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamFrames",
+			Handler:       _CamService_StreamFrames_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "camrpc/camrpc.proto",
+}
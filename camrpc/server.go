@@ -0,0 +1,114 @@
+// Package camrpc implements the CamService gRPC API defined in
+// camrpc.proto, backed by a framehub.FrameHub. It exists alongside the
+// MJPEG HTTP endpoints in server.go so HTTP/2-capable clients (mobile apps,
+// embedded devices) can multiplex a frame stream over a single connection
+// instead of holding open a multipart/x-mixed-replace response.
+package camrpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/lsnow99/cam-server/framehub"
+)
+
+// HubResolver returns the FrameHub a Server should currently stream from,
+// looked up fresh on every RPC so a SIGHUP config reload that replaces the
+// underlying camera (and its hub) takes effect immediately instead of the
+// server being stuck on a hub captured at startup. The second return value
+// is false if there's no hub to serve right now (e.g. no cameras
+// configured).
+type HubResolver func() (*framehub.FrameHub, bool)
+
+// Server implements CamServiceServer on top of whatever hub resolveHub
+// currently points to.
+type Server struct {
+	UnimplementedCamServiceServer
+
+	resolveHub HubResolver
+}
+
+// NewServer returns a Server that streams frames from the hub resolveHub
+// returns, resolved fresh on every RPC.
+func NewServer(resolveHub HubResolver) *Server {
+	return &Server{resolveHub: resolveHub}
+}
+
+// Snapshot returns the most recently published frame.
+func (s *Server) Snapshot(ctx context.Context, req *SnapshotRequest) (*Frame, error) {
+	hub, ok := s.resolveHub()
+	if !ok {
+		return nil, status.Error(codes.Unavailable, "no camera available")
+	}
+
+	sub := hub.Subscribe(ctx)
+	defer sub.Close()
+
+	select {
+	case frame, ok := <-sub.Frames():
+		if !ok {
+			return nil, ctx.Err()
+		}
+		return toProto(frame), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// StreamFrames subscribes to the hub and streams frames to the client,
+// honoring req's max_fps throttle, only_on_change flag and since_seq
+// resume point.
+func (s *Server) StreamFrames(req *StreamRequest, stream CamService_StreamFramesServer) error {
+	ctx := stream.Context()
+
+	hub, ok := s.resolveHub()
+	if !ok {
+		return status.Error(codes.Unavailable, "no camera available")
+	}
+
+	sub := hub.Subscribe(ctx)
+	defer sub.Close()
+
+	var minInterval time.Duration
+	if req.GetMaxFps() > 0 {
+		minInterval = time.Duration(float64(time.Second) / req.GetMaxFps())
+	}
+
+	var lastSent time.Time
+	var lastSeq uint32
+	for {
+		select {
+		case frame, ok := <-sub.Frames():
+			if !ok {
+				return ctx.Err()
+			}
+			if frame.Seq <= req.GetSinceSeq() {
+				continue
+			}
+			if req.GetOnlyOnChange() && !lastSent.IsZero() && frame.Seq == lastSeq {
+				continue
+			}
+			if minInterval > 0 && !lastSent.IsZero() && time.Since(lastSent) < minInterval {
+				continue
+			}
+			if err := stream.Send(toProto(frame)); err != nil {
+				return err
+			}
+			lastSent = time.Now()
+			lastSeq = frame.Seq
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func toProto(frame *framehub.Frame) *Frame {
+	return &Frame{
+		Jpeg:      frame.Data.Bytes(),
+		UnixNanos: frame.Time.UnixNano(),
+		Seq:       frame.Seq,
+	}
+}
@@ -0,0 +1,142 @@
+package camrpc
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/lsnow99/cam-server/framehub"
+)
+
+const bufSize = 1024 * 1024
+
+// dial spins up a gRPC server backed by hub over an in-memory bufconn
+// listener and returns a client connected to it. The caller is responsible
+// for closing both the returned connection and stopping the server via the
+// returned stop func.
+func dial(t *testing.T, hub *framehub.FrameHub) (CamServiceClient, func()) {
+	t.Helper()
+
+	lis := bufconn.Listen(bufSize)
+	srv := grpc.NewServer()
+	RegisterCamServiceServer(srv, NewServer(func() (*framehub.FrameHub, bool) { return hub, true }))
+	go srv.Serve(lis)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dialing bufnet: %v", err)
+	}
+
+	return NewCamServiceClient(conn), func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestSnapshotReturnsLatestFrame(t *testing.T) {
+	hub := framehub.New()
+	hub.Publish(bytes.NewBufferString("frame-1"))
+
+	client, stop := dial(t, hub)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	frame, err := client.Snapshot(ctx, &SnapshotRequest{})
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if string(frame.Jpeg) != "frame-1" {
+		t.Fatalf("got jpeg %q, want %q", frame.Jpeg, "frame-1")
+	}
+	if frame.Seq != 1 {
+		t.Fatalf("got seq %d, want 1", frame.Seq)
+	}
+}
+
+func TestStreamFramesDeliversPublishedFrames(t *testing.T) {
+	hub := framehub.New()
+
+	client, stop := dial(t, hub)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamFrames(ctx, &StreamRequest{})
+	if err != nil {
+		t.Fatalf("StreamFrames: %v", err)
+	}
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			hub.Publish(bytes.NewBufferString("frame"))
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	var seqs []uint32
+	for len(seqs) < 3 {
+		frame, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		seqs = append(seqs, frame.Seq)
+	}
+
+	for i, seq := range seqs {
+		if seq != uint32(i+1) {
+			t.Fatalf("frame %d: got seq %d, want %d", i, seq, i+1)
+		}
+	}
+}
+
+func TestStreamFramesHonorsSinceSeq(t *testing.T) {
+	hub := framehub.New()
+	hub.Publish(bytes.NewBufferString("frame-1"))
+	hub.Publish(bytes.NewBufferString("frame-2"))
+
+	client, stop := dial(t, hub)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamFrames(ctx, &StreamRequest{SinceSeq: 2})
+	if err != nil {
+		t.Fatalf("StreamFrames: %v", err)
+	}
+
+	done := make(chan *Frame, 1)
+	go func() {
+		hub.Publish(bytes.NewBufferString("frame-3"))
+		frame, err := stream.Recv()
+		if err != nil {
+			return
+		}
+		done <- frame
+	}()
+
+	select {
+	case frame := <-done:
+		if frame.Seq != 3 {
+			t.Fatalf("got seq %d, want 3 (frames <= since_seq should be skipped)", frame.Seq)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for frame after since_seq")
+	}
+}
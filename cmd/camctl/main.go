@@ -0,0 +1,72 @@
+// Command camctl is a minimal example client for CamService: it connects to
+// a cam-server gRPC endpoint and either fetches a single snapshot or streams
+// frames to disk, one JPEG file per frame received.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/lsnow99/cam-server/camrpc"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:7677", "cam-server gRPC address")
+	mode := flag.String("mode", "snapshot", `"snapshot" or "stream"`)
+	outDir := flag.String("out", ".", "directory to write received JPEGs to")
+	maxFps := flag.Float64("max-fps", 0, "cap stream rate (0 = uncapped), only used in stream mode")
+	sinceSeq := flag.Uint("since-seq", 0, "resume a stream after this sequence number, only used in stream mode")
+	flag.Parse()
+
+	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatalf("dialing %s: %v", *addr, err)
+	}
+	defer conn.Close()
+
+	client := camrpc.NewCamServiceClient(conn)
+	ctx := context.Background()
+
+	switch *mode {
+	case "snapshot":
+		frame, err := client.Snapshot(ctx, &camrpc.SnapshotRequest{})
+		if err != nil {
+			log.Fatalf("Snapshot: %v", err)
+		}
+		if err := writeFrame(*outDir, frame.Seq, frame.Jpeg); err != nil {
+			log.Fatal(err)
+		}
+	case "stream":
+		stream, err := client.StreamFrames(ctx, &camrpc.StreamRequest{
+			MaxFps:   *maxFps,
+			SinceSeq: uint32(*sinceSeq),
+		})
+		if err != nil {
+			log.Fatalf("StreamFrames: %v", err)
+		}
+		for {
+			frame, err := stream.Recv()
+			if err != nil {
+				log.Fatalf("Recv: %v", err)
+			}
+			if err := writeFrame(*outDir, frame.Seq, frame.Jpeg); err != nil {
+				log.Fatal(err)
+			}
+		}
+	default:
+		log.Fatalf("unknown -mode %q, want \"snapshot\" or \"stream\"", *mode)
+	}
+}
+
+func writeFrame(outDir string, seq uint32, jpeg []byte) error {
+	name := fmt.Sprintf("%d-%d.jpg", time.Now().Unix(), seq)
+	return ioutil.WriteFile(path.Join(outDir, name), jpeg, 0644)
+}
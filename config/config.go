@@ -0,0 +1,131 @@
+// Package config loads the cam-server camera configuration file referenced
+// by the APP_CONFIG env var. The file may be YAML or JSON; the format is
+// inferred from the file extension.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Timelapse holds the per-camera timelapse settings.
+type Timelapse struct {
+	// Enabled turns the timelapse worker on or off for this camera.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// IntervalMins is the number of minutes between timelapse stills.
+	IntervalMins int `json:"interval_mins" yaml:"interval_mins"`
+	// OutputDir is the directory timelapse stills and segments are written to.
+	OutputDir string `json:"output_dir" yaml:"output_dir"`
+	// KeepStills, when false, feeds each JPEG still to the timelapse encoder
+	// without writing it to OutputDir.
+	KeepStills bool `json:"keep_stills" yaml:"keep_stills"`
+}
+
+// Motion holds the per-camera motion-detection settings.
+type Motion struct {
+	// Enabled turns motion detection and event recording on or off for this camera.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// MinOnSecs is how long motion must be continuously detected before an
+	// event recording starts, to debounce single-frame false positives.
+	MinOnSecs float64 `json:"min_on_secs" yaml:"min_on_secs"`
+	// CooldownSecs is how long motion must be continuously absent before an
+	// in-progress event recording is closed.
+	CooldownSecs float64 `json:"cooldown_secs" yaml:"cooldown_secs"`
+	// PreRollSecs is how many seconds of frames from before motion was
+	// detected are prepended to each event recording.
+	PreRollSecs int `json:"preroll_secs" yaml:"preroll_secs"`
+	// Threshold is the per-pixel grayscale difference (0-255) against the
+	// background frame required for a pixel to count as changed.
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	// MinAreaFrac is the fraction, in [0,1], of the downscaled frame that
+	// the largest connected group of changed pixels must cover to count as
+	// motion.
+	MinAreaFrac float64 `json:"min_area_frac" yaml:"min_area_frac"`
+	// EventsDir is the directory event recordings and thumbnails are
+	// written to.
+	EventsDir string `json:"events_dir" yaml:"events_dir"`
+}
+
+// Camera describes a single camera device and how to stream and archive it.
+type Camera struct {
+	// Name identifies the camera in routes (/cam/{name}/...) and logs. Must
+	// be unique across the config file.
+	Name string `json:"name" yaml:"name"`
+	// Device is the V4L2 device path, e.g. /dev/video0.
+	Device string `json:"device" yaml:"device"`
+	// Width and Height are the desired capture resolution.
+	Width  uint32 `json:"width" yaml:"width"`
+	Height uint32 `json:"height" yaml:"height"`
+	// PixelFormats lists acceptable pixel formats in preference order, e.g.
+	// ["MJPEG", "PJPG", "YUYV"]. The first one the device supports is used.
+	PixelFormats []string `json:"pixel_formats" yaml:"pixel_formats"`
+	// FPS is the target capture frame rate. It is not passed to the device:
+	// StreamWorker sets only the pixel format and resolution.
+	FPS int `json:"fps" yaml:"fps"`
+	// Timelapse holds this camera's timelapse settings.
+	Timelapse Timelapse `json:"timelapse" yaml:"timelapse"`
+	// Motion holds this camera's motion-detection settings.
+	Motion Motion `json:"motion" yaml:"motion"`
+}
+
+// Config is the top-level APP_CONFIG document: a list of cameras to stream
+// and archive.
+type Config struct {
+	Cameras []Camera `json:"cameras" yaml:"cameras"`
+}
+
+// Load reads and parses the config file at path, inferring YAML vs JSON
+// from its extension (.yaml/.yml vs .json).
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	var cfg Config
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &cfg); err != nil {
+			return nil, fmt.Errorf("parsing yaml config %s: %w", path, err)
+		}
+	case ".json":
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return nil, fmt.Errorf("parsing json config %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized config extension %q, want .yaml, .yml, or .json", ext)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Cameras) == 0 {
+		return fmt.Errorf("config must define at least one camera")
+	}
+
+	seen := make(map[string]bool, len(c.Cameras))
+	for _, cam := range c.Cameras {
+		if cam.Name == "" {
+			return fmt.Errorf("camera with device %q is missing a name", cam.Device)
+		}
+		if seen[cam.Name] {
+			return fmt.Errorf("duplicate camera name %q", cam.Name)
+		}
+		seen[cam.Name] = true
+
+		if cam.Device == "" {
+			return fmt.Errorf("camera %q is missing a device path", cam.Name)
+		}
+	}
+
+	return nil
+}
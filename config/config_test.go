@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTemp(t *testing.T, name, contents string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(contents), 0644); err != nil {
+		t.Fatalf("writing temp config: %v", err)
+	}
+	return p
+}
+
+func TestLoadYAML(t *testing.T) {
+	p := writeTemp(t, "cams.yaml", `
+cameras:
+  - name: front
+    device: /dev/video0
+    width: 1920
+    height: 1080
+    pixel_formats: [MJPEG, PJPG, YUYV]
+    fps: 30
+    timelapse:
+      enabled: true
+      interval_mins: 5
+      output_dir: tl_front
+      keep_stills: true
+    motion:
+      enabled: true
+      min_on_secs: 1
+      cooldown_secs: 10
+      preroll_secs: 5
+      threshold: 25
+      min_area_frac: 0.02
+      events_dir: events_front
+  - name: back
+    device: /dev/video1
+    width: 1280
+    height: 720
+    pixel_formats: [YUYV]
+    fps: 15
+    timelapse:
+      enabled: false
+`)
+
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Cameras) != 2 {
+		t.Fatalf("got %d cameras, want 2", len(cfg.Cameras))
+	}
+	if cfg.Cameras[0].Name != "front" || cfg.Cameras[0].Width != 1920 {
+		t.Fatalf("unexpected first camera: %+v", cfg.Cameras[0])
+	}
+	if !cfg.Cameras[0].Motion.Enabled || cfg.Cameras[0].Motion.EventsDir != "events_front" {
+		t.Fatalf("unexpected first camera motion settings: %+v", cfg.Cameras[0].Motion)
+	}
+	if cfg.Cameras[1].Name != "back" || cfg.Cameras[1].Timelapse.Enabled {
+		t.Fatalf("unexpected second camera: %+v", cfg.Cameras[1])
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	p := writeTemp(t, "cams.json", `{
+		"cameras": [
+			{"name": "front", "device": "/dev/video0", "width": 640, "height": 480, "pixel_formats": ["MJPEG"], "fps": 30,
+			 "timelapse": {"enabled": true, "interval_mins": 10, "output_dir": "tl", "keep_stills": false}}
+		]
+	}`)
+
+	cfg, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Cameras) != 1 || cfg.Cameras[0].Name != "front" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadRejectsEmptyCameraList(t *testing.T) {
+	p := writeTemp(t, "empty.yaml", `cameras: []`)
+	if _, err := Load(p); err == nil {
+		t.Fatal("expected error for empty camera list, got nil")
+	}
+}
+
+func TestLoadRejectsDuplicateNames(t *testing.T) {
+	p := writeTemp(t, "dupe.yaml", `
+cameras:
+  - name: front
+    device: /dev/video0
+  - name: front
+    device: /dev/video1
+`)
+	if _, err := Load(p); err == nil {
+		t.Fatal("expected error for duplicate camera names, got nil")
+	}
+}
+
+func TestLoadRejectsUnknownExtension(t *testing.T) {
+	p := writeTemp(t, "cams.toml", `cameras = []`)
+	if _, err := Load(p); err == nil {
+		t.Fatal("expected error for unrecognized extension, got nil")
+	}
+}
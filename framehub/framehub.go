@@ -0,0 +1,156 @@
+// Package framehub provides a small pub/sub hub for broadcasting encoded
+// camera frames to an arbitrary number of independent subscribers.
+//
+// The previous design pushed each frame onto a single shared channel up to
+// N times and relied on every reader performing a throwaway receive to clear
+// stale frames before asking for a fresh one. That scheme meant a slow
+// reader (or one that forgot the throwaway receive) could stall the frame
+// producer and starve every other reader. FrameHub instead gives each
+// subscriber its own small buffered channel and drops the oldest buffered
+// frame when a subscriber falls behind, so one slow client never blocks the
+// producer or any other subscriber.
+package framehub
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// subscriberBufSize is the number of frames buffered per subscriber before
+// the oldest buffered frame is dropped to make room for the newest one.
+const subscriberBufSize = 2
+
+// Frame is a single encoded frame published to a FrameHub, tagged with the
+// metadata needed by consumers like the gRPC API that can't just hand a raw
+// *bytes.Buffer over the wire.
+type Frame struct {
+	// Data is the encoded (JPEG) frame bytes.
+	Data *bytes.Buffer
+	// Seq is a monotonically increasing sequence number assigned in
+	// Publish order, starting at 1. It lets clients detect gaps and resume
+	// a stream from a known point.
+	Seq uint32
+	// Time is when the frame was published to the hub.
+	Time time.Time
+}
+
+// FrameHub fans out encoded frames published by a single producer to any
+// number of subscribers. It is safe for concurrent use.
+type FrameHub struct {
+	mu      sync.Mutex
+	subs    map[*Subscriber]struct{}
+	latest  *Frame
+	nextSeq uint32
+}
+
+// New creates an empty FrameHub ready to accept subscribers and published
+// frames.
+func New() *FrameHub {
+	return &FrameHub{
+		subs: make(map[*Subscriber]struct{}),
+	}
+}
+
+// Subscriber receives frames published to a FrameHub. Callers must call
+// Close when they are done receiving, typically via defer right after
+// Subscribe.
+type Subscriber struct {
+	hub    *FrameHub
+	ch     chan *Frame
+	cancel context.CancelFunc
+}
+
+// Frames returns the channel new frames are delivered on. The channel is
+// closed once the subscriber is closed, either explicitly or because its
+// context was cancelled.
+func (s *Subscriber) Frames() <-chan *Frame {
+	return s.ch
+}
+
+// Close unsubscribes from the hub and closes the frame channel. Close is
+// safe to call more than once.
+func (s *Subscriber) Close() {
+	s.cancel()
+}
+
+// Subscribe registers a new subscriber with the hub. The returned
+// Subscriber is automatically closed when ctx is done, so callers that
+// derive ctx from a request or connection lifetime don't need to plumb a
+// separate cancellation signal through to Close.
+//
+// If a frame has already been published, it is delivered to the new
+// subscriber immediately so late joiners don't have to wait for the next
+// tick of the producer.
+func (h *FrameHub) Subscribe(ctx context.Context) *Subscriber {
+	ctx, cancel := context.WithCancel(ctx)
+
+	sub := &Subscriber{
+		ch: make(chan *Frame, subscriberBufSize),
+	}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	if h.latest != nil {
+		// sub.ch is freshly made and not yet reachable from Publish (which
+		// also takes h.mu), so it has room and this can't block; doing the
+		// enqueue inside the same critical section as the subs registration
+		// above ensures no Publish can land between the two and deliver a
+		// newer frame to sub ahead of this replay.
+		sub.ch <- h.latest
+	}
+	h.mu.Unlock()
+
+	sub.hub = h
+	sub.cancel = func() {
+		cancel()
+		h.mu.Lock()
+		if _, ok := h.subs[sub]; ok {
+			delete(h.subs, sub)
+			close(sub.ch)
+		}
+		h.mu.Unlock()
+	}
+
+	go func() {
+		<-ctx.Done()
+		sub.cancel()
+	}()
+
+	return sub
+}
+
+// Publish broadcasts buf to every current subscriber, tagging it with the
+// next sequence number and the current time. Subscribers that are already
+// at capacity have their oldest buffered frame dropped to make room, so
+// Publish never blocks waiting on a slow reader.
+func (h *FrameHub) Publish(buf *bytes.Buffer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextSeq++
+	frame := &Frame{
+		Data: buf,
+		Seq:  h.nextSeq,
+		Time: time.Now(),
+	}
+	h.latest = frame
+
+	for sub := range h.subs {
+		select {
+		case sub.ch <- frame:
+		default:
+			// Subscriber's buffer is full; drop the oldest frame and retry
+			// once so the subscriber always has room for the newest frame.
+			select {
+			case <-sub.ch:
+			default:
+			}
+			select {
+			case sub.ch <- frame:
+			default:
+			}
+		}
+	}
+}
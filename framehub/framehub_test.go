@@ -0,0 +1,187 @@
+package framehub
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSubscribeReceivesLatestOnJoin verifies a late-joining subscriber
+// immediately sees the most recently published frame.
+func TestSubscribeReceivesLatestOnJoin(t *testing.T) {
+	h := New()
+	h.Publish(bytes.NewBufferString("frame-1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := h.Subscribe(ctx)
+	defer sub.Close()
+
+	select {
+	case frame := <-sub.Frames():
+		if frame.Data.String() != "frame-1" {
+			t.Fatalf("got %q, want %q", frame.Data.String(), "frame-1")
+		}
+		if frame.Seq != 1 {
+			t.Fatalf("got seq %d, want 1", frame.Seq)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for latest frame")
+	}
+}
+
+// TestSlowSubscriberDoesNotBlockFastSubscribers publishes many frames while
+// one subscriber never reads. Every other subscriber must still receive
+// frames; Publish must never block.
+func TestSlowSubscriberDoesNotBlockFastSubscribers(t *testing.T) {
+	h := New()
+
+	const numFast = 50
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	slow := h.Subscribe(ctx)
+	defer slow.Close()
+
+	fastSubs := make([]*Subscriber, numFast)
+	for i := range fastSubs {
+		fastSubs[i] = h.Subscribe(ctx)
+		defer fastSubs[i].Close()
+	}
+
+	var wg sync.WaitGroup
+	received := make([]int, numFast)
+	for i, sub := range fastSubs {
+		wg.Add(1)
+		go func(i int, sub *Subscriber) {
+			defer wg.Done()
+			for range sub.Frames() {
+				received[i]++
+			}
+		}(i, sub)
+	}
+
+	const numFrames = 100
+	for i := 0; i < numFrames; i++ {
+		done := make(chan struct{})
+		go func() {
+			h.Publish(bytes.NewBufferString("frame"))
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Publish blocked on slow subscriber at frame %d", i)
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	for i, n := range received {
+		if n == 0 {
+			t.Errorf("fast subscriber %d never received any frames", i)
+		}
+	}
+}
+
+// TestManySubscribersDropOldestOnOverflow spawns a mix of fast and slow
+// subscribers concurrently and checks that publishing still completes
+// without deadlocking while the stalled subscribers' buffers overflow.
+func TestManySubscribersDropOldestOnOverflow(t *testing.T) {
+	h := New()
+
+	const numSlow = 20
+	const numFast = 20
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < numSlow; i++ {
+		sub := h.Subscribe(ctx)
+		wg.Add(1)
+		go func(sub *Subscriber) {
+			defer wg.Done()
+			defer sub.Close()
+			// Slow subscriber reads once, then stalls for the rest of the
+			// test to simulate a stuck client.
+			<-sub.Frames()
+			<-ctx.Done()
+		}(sub)
+	}
+
+	for i := 0; i < numFast; i++ {
+		sub := h.Subscribe(ctx)
+		wg.Add(1)
+		go func(sub *Subscriber) {
+			defer wg.Done()
+			defer sub.Close()
+			for {
+				select {
+				case <-sub.Frames():
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(sub)
+	}
+
+	var publishWg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		publishWg.Add(1)
+		go func() {
+			defer publishWg.Done()
+			h.Publish(bytes.NewBufferString("frame"))
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		publishWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publishing to many subscribers deadlocked")
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+// TestSubscriberCloseIsIdempotent ensures calling Close multiple times does
+// not panic.
+func TestSubscriberCloseIsIdempotent(t *testing.T) {
+	h := New()
+	sub := h.Subscribe(context.Background())
+	sub.Close()
+	sub.Close()
+
+	if _, ok := <-sub.Frames(); ok {
+		t.Fatal("expected frame channel to be closed")
+	}
+}
+
+// TestCloseRemovesSubscriberFromHub verifies a closed subscriber no longer
+// receives frames published after it unsubscribes.
+func TestCloseRemovesSubscriberFromHub(t *testing.T) {
+	h := New()
+	sub := h.Subscribe(context.Background())
+	sub.Close()
+
+	h.Publish(bytes.NewBufferString("frame-after-close"))
+
+	h.mu.Lock()
+	_, stillSubscribed := h.subs[sub]
+	h.mu.Unlock()
+
+	if stillSubscribed {
+		t.Fatal("closed subscriber is still registered with the hub")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/lsnow99/cam-server/camrpc"
+	"github.com/lsnow99/cam-server/framehub"
+)
+
+// ServeGRPC starts the CamService gRPC server on port, streaming the same
+// default camera the legacy /snap, /stream, and /timelapse HTTP aliases
+// serve. The default camera's hub is resolved fresh from manager on every
+// RPC (as HandleCam does for HTTP) rather than captured once at startup, so
+// a SIGHUP reload that replaces the default camera takes effect immediately
+// instead of leaving the gRPC server stuck on a dead hub.
+func ServeGRPC(ctx context.Context, errCh chan error, wg *sync.WaitGroup, port string, manager *CameraManager) {
+	// This is guaranteed to run as the last thing before this function returns
+	defer wg.Done()
+
+	log.Printf("Starting gRPC server on port %s", port)
+
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		errCh <- err
+		return
+	}
+
+	resolveHub := func() (*framehub.FrameHub, bool) {
+		rt, ok := manager.Get(manager.DefaultName())
+		if !ok {
+			return nil, false
+		}
+		return rt.hub, true
+	}
+
+	srv := grpc.NewServer()
+	camrpc.RegisterCamServiceServer(srv, camrpc.NewServer(resolveHub))
+
+	// Receives a signal from the done channel to stop the server
+	go func() {
+		<-ctx.Done()
+		log.Println("Gracefully shutting down gRPC server")
+
+		// GracefulStop waits for in-flight RPCs (including long-lived
+		// StreamFrames calls) to finish. If a stuck stream hasn't
+		// finished within our graceful shutdown budget, force close it
+		// rather than hang the whole process.
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second * time.Duration(GracefulTimeoutSecs-1)):
+			srv.Stop()
+		}
+	}()
+
+	/*
+		`srv.Serve()` blocks until an error, then returns it. We report any
+		errors to the main synchronization goroutine via the `errCh` channel,
+		same as ServeHttp does for the HTTP server. ErrServerStopped from the
+		above GracefulStop/Stop call is harmless since errCh is buffered.
+	*/
+	errCh <- srv.Serve(lis)
+
+	log.Println("Stopped gRPC server")
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"log"
 	"os"
@@ -10,6 +9,8 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/lsnow99/cam-server/config"
 )
 
 // Version flag set during compilation
@@ -18,6 +19,9 @@ var Version string
 // Port the default port to be used. Can be overrided with APP_PORT env var
 var Port = "7676"
 
+// GrpcPort the default port for the CamService gRPC server. Can be overrided with APP_GRPC_PORT env var
+var GrpcPort = "7677"
+
 // ReadTimeout total time for reading the entire request including body
 var ReadTimeout = time.Second * 5
 
@@ -30,12 +34,85 @@ var WebcamFrameTimeoutSecs = 5
 // WebcamFrameMaxTimeouts maximum consecutive timeouts for grabbing webcam frame before shutting down
 var WebcamFrameMaxTimeouts = 10
 
-// TimelapseIntervalMins minutes interval between timelapse stills, override with APP_TIMELAPSE_INT_MINS
+// TimelapseIntervalMins minutes interval between timelapse stills, override with APP_TIMELAPSE_INT_MINS.
+// Only used to build the single-camera default config when APP_CONFIG isn't set.
 var TimelapseIntervalMins = 5
 
-// TimelapseOutputDir directory for timelapse output files
+// TimelapseOutputDir directory for timelapse output files. Only used to
+// build the single-camera default config when APP_CONFIG isn't set.
 var TimelapseOutputDir = "tl_output"
 
+// TimelapseKeepStills whether to keep the individual JPEG stills on disk
+// after they've been fed to the timelapse encoder, override with
+// APP_TIMELAPSE_KEEP_STILLS. Only used to build the single-camera default
+// config when APP_CONFIG isn't set.
+var TimelapseKeepStills = true
+
+// MotionEnabled turns on motion-detection event recording, override with
+// APP_MOTION_ENABLED. Only used to build the single-camera default config
+// when APP_CONFIG isn't set.
+var MotionEnabled = false
+
+// MotionMinOnSecs seconds motion must be continuously detected before an
+// event recording starts, override with APP_MOTION_MIN_ON_SECS.
+var MotionMinOnSecs = 1.0
+
+// MotionCooldownSecs seconds motion must be continuously absent before an
+// in-progress event recording is closed, override with
+// APP_MOTION_COOLDOWN_SECS.
+var MotionCooldownSecs = 10.0
+
+// MotionPreRollSecs seconds of frames from before motion was detected to
+// prepend to each event recording, override with APP_MOTION_PREROLL_SECS.
+var MotionPreRollSecs = 5
+
+// MotionThreshold per-pixel grayscale difference (0-255) against the
+// background required to count as changed, override with
+// APP_MOTION_THRESHOLD.
+var MotionThreshold = 25.0
+
+// MotionMinAreaFrac fraction, in [0,1], of the downscaled frame the largest
+// connected group of changed pixels must cover to count as motion, override
+// with APP_MOTION_MIN_AREA_FRAC.
+var MotionMinAreaFrac = 0.02
+
+// MotionEventsDir directory motion event recordings are written under,
+// override with APP_MOTION_DIR.
+var MotionEventsDir = "events"
+
+// defaultConfig builds the single-camera config this server ran with
+// before APP_CONFIG existed, from the individual APP_* env vars, so
+// existing deployments don't have to write a config file to upgrade.
+func defaultConfig() config.Config {
+	return config.Config{
+		Cameras: []config.Camera{
+			{
+				Name:         "default",
+				Device:       "/dev/video0",
+				Width:        1920,
+				Height:       1080,
+				PixelFormats: []string{"MJPEG", "PJPG", "YUYV"},
+				FPS:          30,
+				Timelapse: config.Timelapse{
+					Enabled:      true,
+					IntervalMins: TimelapseIntervalMins,
+					OutputDir:    TimelapseOutputDir,
+					KeepStills:   TimelapseKeepStills,
+				},
+				Motion: config.Motion{
+					Enabled:      MotionEnabled,
+					MinOnSecs:    MotionMinOnSecs,
+					CooldownSecs: MotionCooldownSecs,
+					PreRollSecs:  MotionPreRollSecs,
+					Threshold:    MotionThreshold,
+					MinAreaFrac:  MotionMinAreaFrac,
+					EventsDir:    MotionEventsDir,
+				},
+			},
+		},
+	}
+}
+
 func main() {
 
 	if Version == "" {
@@ -56,6 +133,15 @@ func main() {
 		log.Printf("No port provided (APP_PORT), using %s as default", Port)
 	}
 
+	if gp := os.Getenv("APP_GRPC_PORT"); gp != "" {
+		if _, err := strconv.Atoi(gp); err != nil {
+			log.Fatal("failed to parse env var APP_GRPC_PORT as integer")
+		}
+		GrpcPort = gp
+	} else {
+		log.Printf("No gRPC port provided (APP_GRPC_PORT), using %s as default", GrpcPort)
+	}
+
 	if tim := os.Getenv("APP_TIMELAPSE_INT_MINS"); tim != "" {
 		temp, err := strconv.Atoi(tim)
 		if err != nil {
@@ -72,6 +158,84 @@ func main() {
 		log.Printf("No timelapse output dir provided provided (APP_TIMELAPSE_DIR), using %s as default", TimelapseOutputDir)
 	}
 
+	if ks := os.Getenv("APP_TIMELAPSE_KEEP_STILLS"); ks != "" {
+		temp, err := strconv.ParseBool(ks)
+		if err != nil {
+			log.Fatal("failed to parse env var APP_TIMELAPSE_KEEP_STILLS as bool")
+		}
+		TimelapseKeepStills = temp
+	} else {
+		log.Printf("No timelapse keep-stills setting provided (APP_TIMELAPSE_KEEP_STILLS), using %t as default", TimelapseKeepStills)
+	}
+
+	if me := os.Getenv("APP_MOTION_ENABLED"); me != "" {
+		temp, err := strconv.ParseBool(me)
+		if err != nil {
+			log.Fatal("failed to parse env var APP_MOTION_ENABLED as bool")
+		}
+		MotionEnabled = temp
+	} else {
+		log.Printf("No motion detection setting provided (APP_MOTION_ENABLED), using %t as default", MotionEnabled)
+	}
+
+	if mo := os.Getenv("APP_MOTION_MIN_ON_SECS"); mo != "" {
+		temp, err := strconv.ParseFloat(mo, 64)
+		if err != nil {
+			log.Fatal("failed to parse env var APP_MOTION_MIN_ON_SECS as float")
+		}
+		MotionMinOnSecs = temp
+	}
+
+	if mc := os.Getenv("APP_MOTION_COOLDOWN_SECS"); mc != "" {
+		temp, err := strconv.ParseFloat(mc, 64)
+		if err != nil {
+			log.Fatal("failed to parse env var APP_MOTION_COOLDOWN_SECS as float")
+		}
+		MotionCooldownSecs = temp
+	}
+
+	if mp := os.Getenv("APP_MOTION_PREROLL_SECS"); mp != "" {
+		temp, err := strconv.Atoi(mp)
+		if err != nil {
+			log.Fatal("failed to parse env var APP_MOTION_PREROLL_SECS as integer")
+		}
+		MotionPreRollSecs = temp
+	}
+
+	if mt := os.Getenv("APP_MOTION_THRESHOLD"); mt != "" {
+		temp, err := strconv.ParseFloat(mt, 64)
+		if err != nil {
+			log.Fatal("failed to parse env var APP_MOTION_THRESHOLD as float")
+		}
+		MotionThreshold = temp
+	}
+
+	if ma := os.Getenv("APP_MOTION_MIN_AREA_FRAC"); ma != "" {
+		temp, err := strconv.ParseFloat(ma, 64)
+		if err != nil {
+			log.Fatal("failed to parse env var APP_MOTION_MIN_AREA_FRAC as float")
+		}
+		MotionMinAreaFrac = temp
+	}
+
+	if md := os.Getenv("APP_MOTION_DIR"); md != "" {
+		MotionEventsDir = md
+	}
+
+	configPath := os.Getenv("APP_CONFIG")
+
+	var cfg config.Config
+	if configPath == "" {
+		log.Println("No config file provided (APP_CONFIG), using single default camera built from APP_* env vars")
+		cfg = defaultConfig()
+	} else {
+		loaded, err := config.Load(configPath)
+		if err != nil {
+			log.Fatalf("failed to load config %s: %s", configPath, err)
+		}
+		cfg = *loaded
+	}
+
 	/*
 		Here we are defining our context, which is like a grouping of goroutines.
 		We can cancel the context and it will signal all the goroutines who are
@@ -105,13 +269,33 @@ func main() {
 	*/
 	errCh := make(chan error, 1000)
 
-	li := make(chan *bytes.Buffer)
+	// manager owns one FrameHub + set of workers per configured camera, and
+	// lets us start/stop/restart individual cameras independently (e.g. on
+	// a SIGHUP config reload) without tearing down the whole process.
+	manager := NewCameraManager(ctx, errCh)
+	if err := manager.Apply(cfg.Cameras); err != nil {
+		log.Fatalf("failed to start cameras: %s", err)
+	}
+
+	// Stopping every camera's workers is itself a graceful-shutdown step,
+	// so it's tracked by the same WaitGroup as every other worker below.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		manager.StopAll()
+	}()
+
+	// The gRPC API streams whatever camera is first in config order, same
+	// as the legacy /snap, /stream, and /timelapse HTTP aliases; per-camera
+	// gRPC routing can follow the same /cam/{name} convention as HTTP later.
+	// ServeGRPC resolves that default camera's hub from manager fresh on
+	// every RPC, so it stays correct across SIGHUP reloads.
 
 	// Here we add to the waitgroup a delta equal to the number of workers we are spawning
-	wg.Add(3)
-	go StreamWorker(ctx, errCh, &wg, li)
-	go TimelapseWorker(ctx, errCh, &wg, li)
-	go ServeHttp(ctx, errCh, &wg, Port, li)
+	wg.Add(2)
+	go ServeHttp(ctx, errCh, &wg, Port, manager)
+	go ServeGRPC(ctx, errCh, &wg, GrpcPort, manager)
 
 	/*
 		Define a buffered channel to listen to stop signals and handle the first one gracefully
@@ -119,6 +303,31 @@ func main() {
 	sigs := make(chan os.Signal, 2)
 	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 
+	/*
+		Reloads are handled on their own signal channel so a SIGHUP doesn't
+		get consumed by the shutdown select below: reread the config, diff
+		it against what's running, and restart only the affected cameras.
+	*/
+	reloadSigs := make(chan os.Signal, 2)
+	signal.Notify(reloadSigs, syscall.SIGHUP)
+	go func() {
+		for range reloadSigs {
+			if configPath == "" {
+				log.Println("Received SIGHUP but no APP_CONFIG was set, nothing to reload")
+				continue
+			}
+			log.Println("Received SIGHUP, reloading config")
+			newCfg, err := config.Load(configPath)
+			if err != nil {
+				log.Printf("failed to reload config %s, keeping existing cameras running: %s", configPath, err)
+				continue
+			}
+			if err := manager.Apply(newCfg.Cameras); err != nil {
+				log.Printf("failed to apply reloaded config: %s", err)
+			}
+		}
+	}()
+
 	/*
 		Here we use a select statement which blocks until either one of the channel statements in the
 		case statements activate. The program proceeds after executing the case block. In this case,
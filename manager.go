@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"reflect"
+	"sync"
+
+	"github.com/lsnow99/cam-server/config"
+	"github.com/lsnow99/cam-server/framehub"
+	"github.com/lsnow99/cam-server/motion"
+)
+
+// cameraRuntime is everything spawned for a single configured camera: its
+// own frame hub, its own timelapse controller and motion detector (if
+// enabled for it), and the cancel func/WaitGroup needed to stop its workers
+// independently of every other camera.
+type cameraRuntime struct {
+	cfg    config.Camera
+	hub    *framehub.FrameHub
+	tc     *TimelapseController
+	motion *motion.Detector
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// CameraManager owns the set of currently-running cameras and can start,
+// stop, and reload them as the config changes, independent of any one
+// camera's workers.
+type CameraManager struct {
+	parentCtx context.Context
+	errCh     chan error
+
+	mu      sync.Mutex
+	cameras map[string]*cameraRuntime
+	// order preserves config file order; order[0] is the camera the legacy
+	// /snap, /stream, /timelapse routes alias.
+	order []string
+}
+
+// NewCameraManager returns a manager with no cameras running. Call Apply to
+// start the initial set from a loaded config.Config.
+func NewCameraManager(ctx context.Context, errCh chan error) *CameraManager {
+	return &CameraManager{
+		parentCtx: ctx,
+		errCh:     errCh,
+		cameras:   make(map[string]*cameraRuntime),
+	}
+}
+
+// Apply reconciles the running cameras with cams: cameras present in cams
+// but not currently running are started, cameras currently running but
+// absent from cams (or whose config changed) are stopped and, if still
+// present, restarted with the new config. Cameras whose config is
+// unchanged are left running untouched.
+func (m *CameraManager) Apply(cams []config.Camera) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wanted := make(map[string]config.Camera, len(cams))
+	order := make([]string, 0, len(cams))
+	for _, cam := range cams {
+		wanted[cam.Name] = cam
+		order = append(order, cam.Name)
+	}
+
+	// Stop cameras that were removed entirely or whose config changed;
+	// changed ones get started fresh below with their new config.
+	for name, rt := range m.cameras {
+		newCfg, stillWanted := wanted[name]
+		if !stillWanted || !reflect.DeepEqual(newCfg, rt.cfg) {
+			m.stopLocked(name)
+		}
+	}
+
+	for _, cam := range cams {
+		if _, running := m.cameras[cam.Name]; running {
+			continue
+		}
+		if err := m.startLocked(cam); err != nil {
+			return fmt.Errorf("starting camera %q: %w", cam.Name, err)
+		}
+	}
+
+	m.order = order
+	return nil
+}
+
+func (m *CameraManager) startLocked(cfg config.Camera) error {
+	ctx, cancel := context.WithCancel(m.parentCtx)
+	hub := framehub.New()
+
+	rt := &cameraRuntime{
+		cfg:    cfg,
+		hub:    hub,
+		cancel: cancel,
+	}
+
+	if cfg.Timelapse.Enabled {
+		outputDir := cfg.Timelapse.OutputDir
+		if outputDir == "" {
+			outputDir = path.Join(TimelapseOutputDir, cfg.Name)
+		}
+		tc, err := NewTimelapseController(outputDir, cfg.Timelapse.KeepStills)
+		if err != nil {
+			cancel()
+			return fmt.Errorf("starting timelapse controller: %w", err)
+		}
+		rt.tc = tc
+	}
+
+	if cfg.Motion.Enabled {
+		eventsDir := cfg.Motion.EventsDir
+		if eventsDir == "" {
+			eventsDir = path.Join(MotionEventsDir, cfg.Name)
+		}
+		det, err := motion.NewDetector(eventsDir, motion.Config{
+			MinOnSecs:    cfg.Motion.MinOnSecs,
+			CooldownSecs: cfg.Motion.CooldownSecs,
+			PreRollSecs:  cfg.Motion.PreRollSecs,
+			Threshold:    cfg.Motion.Threshold,
+			MinAreaFrac:  cfg.Motion.MinAreaFrac,
+		})
+		if err != nil {
+			cancel()
+			// No worker has started yet to close rt.tc via ctx.Done(), so
+			// close it here to avoid leaking its ffmpeg process.
+			if rt.tc != nil {
+				rt.tc.close()
+			}
+			return fmt.Errorf("starting motion detector: %w", err)
+		}
+		rt.motion = det
+	}
+
+	rt.wg.Add(1)
+	go StreamWorker(ctx, m.errCh, &rt.wg, hub, cfg)
+
+	if rt.tc != nil {
+		rt.wg.Add(1)
+		go TimelapseWorker(ctx, m.errCh, &rt.wg, hub, rt.tc, cfg.Timelapse.IntervalMins)
+	}
+
+	if rt.motion != nil {
+		rt.wg.Add(1)
+		go motion.Run(ctx, m.errCh, &rt.wg, hub, rt.motion)
+	}
+
+	m.cameras[cfg.Name] = rt
+	log.Printf("Started camera %q (%s)", cfg.Name, cfg.Device)
+	return nil
+}
+
+func (m *CameraManager) stopLocked(name string) {
+	rt, ok := m.cameras[name]
+	if !ok {
+		return
+	}
+	// Canceling rt's context stops StreamWorker and, if enabled,
+	// TimelapseWorker and the motion detector's Run loop too; both close
+	// their own in-progress recording before returning, so there's nothing
+	// left to clean up here once rt.wg.Wait() returns.
+	rt.cancel()
+	rt.wg.Wait()
+	delete(m.cameras, name)
+	log.Printf("Stopped camera %q", name)
+}
+
+// StopAll stops every running camera. Used during process shutdown.
+func (m *CameraManager) StopAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range m.cameras {
+		m.stopLocked(name)
+	}
+}
+
+// Get returns the runtime for the named camera, if it's currently running.
+func (m *CameraManager) Get(name string) (*cameraRuntime, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rt, ok := m.cameras[name]
+	return rt, ok
+}
+
+// DefaultName returns the name of the first camera in config order, used
+// to resolve the legacy /snap, /stream, and /timelapse aliases. Returns ""
+// if no cameras are configured.
+func (m *CameraManager) DefaultName() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.order) == 0 {
+		return ""
+	}
+	return m.order[0]
+}
+
+// Names returns the names of every currently running camera, in config
+// order.
+func (m *CameraManager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, len(m.order))
+	copy(names, m.order)
+	return names
+}
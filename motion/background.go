@@ -0,0 +1,131 @@
+package motion
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+
+	_ "image/jpeg"
+)
+
+// downscale dimensions the incoming frame is resampled to before comparison.
+// Motion detection only needs a coarse picture of what changed, and working
+// at full camera resolution would make the per-frame diff far more
+// expensive than it needs to be.
+const (
+	downscaleWidth  = 160
+	downscaleHeight = 120
+)
+
+// bgAlpha is the weight given to each new frame when updating the
+// exponentially-weighted background average. Small values make the
+// background adapt slowly, so a real moving subject doesn't get absorbed
+// into the background before cooldown has a chance to end the event.
+const bgAlpha = 0.05
+
+// decodeGray decodes jpeg and downscales it to a fixed-size grayscale image
+// for comparison against the background.
+func decodeGray(jpeg []byte) (*image.Gray, error) {
+	img, _, err := image.Decode(bytes.NewReader(jpeg))
+	if err != nil {
+		return nil, err
+	}
+	return downscaleGray(img), nil
+}
+
+func downscaleGray(img image.Image) *image.Gray {
+	b := img.Bounds()
+	out := image.NewGray(image.Rect(0, 0, downscaleWidth, downscaleHeight))
+	for y := 0; y < downscaleHeight; y++ {
+		sy := b.Min.Y + y*b.Dy()/downscaleHeight
+		for x := 0; x < downscaleWidth; x++ {
+			sx := b.Min.X + x*b.Dx()/downscaleWidth
+			out.SetGray(x, y, color.GrayModel.Convert(img.At(sx, sy)).(color.Gray))
+		}
+	}
+	return out
+}
+
+// background is an exponentially-weighted running average of recent frames,
+// used as the reference a new frame is diffed against to find what changed.
+type background struct {
+	avg []float64
+}
+
+func newBackground(g *image.Gray) *background {
+	avg := make([]float64, len(g.Pix))
+	for i, px := range g.Pix {
+		avg[i] = float64(px)
+	}
+	return &background{avg: avg}
+}
+
+// update diffs g against the current background, then folds g into it, and
+// returns whether the largest connected group of changed pixels covers at
+// least minAreaFrac of the frame.
+func (b *background) update(g *image.Gray, threshold, minAreaFrac float64) bool {
+	changed := make([]bool, len(g.Pix))
+	for i, px := range g.Pix {
+		d := float64(px) - b.avg[i]
+		if d < 0 {
+			d = -d
+		}
+		changed[i] = d > threshold
+		b.avg[i] += bgAlpha * (float64(px) - b.avg[i])
+	}
+
+	return largestComponentFrac(changed, downscaleWidth, downscaleHeight) >= minAreaFrac
+}
+
+// largestComponentFrac returns the size of the largest 4-connected group of
+// true values in mask (a w*h grid), as a fraction of len(mask). A single
+// connected-component check is a cheap way to reject diffuse sensor noise
+// scattered across the frame while still catching one real moving subject.
+func largestComponentFrac(mask []bool, w, h int) float64 {
+	visited := make([]bool, len(mask))
+	var stack []int
+	best := 0
+
+	for start, v := range mask {
+		if !v || visited[start] {
+			continue
+		}
+
+		visited[start] = true
+		stack = append(stack[:0], start)
+		size := 0
+
+		for len(stack) > 0 {
+			idx := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			size++
+
+			x, y := idx%w, idx/w
+			if x > 0 {
+				pushNeighbor(&stack, visited, mask, idx-1)
+			}
+			if x < w-1 {
+				pushNeighbor(&stack, visited, mask, idx+1)
+			}
+			if y > 0 {
+				pushNeighbor(&stack, visited, mask, idx-w)
+			}
+			if y < h-1 {
+				pushNeighbor(&stack, visited, mask, idx+w)
+			}
+		}
+
+		if size > best {
+			best = size
+		}
+	}
+
+	return float64(best) / float64(len(mask))
+}
+
+func pushNeighbor(stack *[]int, visited []bool, mask []bool, idx int) {
+	if mask[idx] && !visited[idx] {
+		visited[idx] = true
+		*stack = append(*stack, idx)
+	}
+}
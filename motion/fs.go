@@ -0,0 +1,15 @@
+package motion
+
+import "os"
+
+// ensureDir creates dir, and any missing parents, if it doesn't already
+// exist.
+func ensureDir(dir string) error {
+	return os.MkdirAll(dir, 0755)
+}
+
+// writeThumbnail writes jpeg to p, used to snapshot the first frame of a
+// new event recording as its thumbnail.
+func writeThumbnail(p string, jpeg []byte) error {
+	return os.WriteFile(p, jpeg, 0644)
+}
@@ -0,0 +1,144 @@
+package motion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"time"
+)
+
+// liveEvent is the JSON payload pushed to GET /events/live subscribers.
+type liveEvent struct {
+	Type string `json:"type"` // "motion_start" or "motion_end"
+	Ts   int64  `json:"ts"`
+}
+
+// eventJSON is the JSON shape returned by HandleList for a single event.
+type eventJSON struct {
+	ID           string  `json:"id"`
+	Timestamp    int64   `json:"timestamp"`
+	DurationSecs float64 `json:"duration_secs"`
+	Thumbnail    string  `json:"thumbnail"`
+}
+
+// HandleList returns every completed event as JSON, oldest first. Thumbnail
+// is a URL path (e.g. "events/{id}.jpg", relative to whichever of / or
+// /cam/{name}/ the events list was requested under) rather than d's
+// server-local filesystem path, so a browser can fetch it directly.
+func (d *Detector) HandleList(w http.ResponseWriter, r *http.Request) {
+	events := d.Events()
+
+	out := make([]eventJSON, len(events))
+	for i, e := range events {
+		var thumbnail string
+		if e.Thumbnail != "" {
+			thumbnail = "events/" + e.ID + ".jpg"
+		}
+		out[i] = eventJSON{
+			ID:           e.ID,
+			Timestamp:    e.Start.Unix(),
+			DurationSecs: e.End.Sub(e.Start).Seconds(),
+			Thumbnail:    thumbnail,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// HandleEventFile serves the recorded MP4 for the event named by id, with
+// byte-range support so a browser can seek it.
+func (d *Detector) HandleEventFile(w http.ResponseWriter, r *http.Request, id string) {
+	d.serveEventFile(w, r, id+".mp4")
+}
+
+// HandleEventThumbnail serves the thumbnail JPEG for the event named by id.
+func (d *Detector) HandleEventThumbnail(w http.ResponseWriter, r *http.Request, id string) {
+	d.serveEventFile(w, r, id+".jpg")
+}
+
+func (d *Detector) serveEventFile(w http.ResponseWriter, r *http.Request, name string) {
+	p := path.Join(d.eventsDir, name)
+
+	f, err := os.Open(p)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeContent(w, r, path.Base(p), info.ModTime(), f)
+}
+
+// HandleLive streams motion_start/motion_end notifications as
+// Server-Sent Events for as long as the client stays connected.
+func (d *Detector) HandleLive(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := make(chan liveEvent, 8)
+	d.addLiveSub(ch)
+	defer d.removeLiveSub(ch)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-ch:
+			b, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", b)
+			flusher.Flush()
+		}
+	}
+}
+
+func (d *Detector) addLiveSub(ch chan liveEvent) {
+	d.liveMu.Lock()
+	defer d.liveMu.Unlock()
+	d.live[ch] = struct{}{}
+}
+
+func (d *Detector) removeLiveSub(ch chan liveEvent) {
+	d.liveMu.Lock()
+	defer d.liveMu.Unlock()
+	delete(d.live, ch)
+}
+
+// broadcastLive notifies every live subscriber of a motion start/end
+// transition. A subscriber that isn't keeping up just misses the
+// notification rather than blocking the detector loop; it can always fall
+// back to GET /events to catch up.
+func (d *Detector) broadcastLive(typ string, ts time.Time) {
+	ev := liveEvent{Type: typ, Ts: ts.Unix()}
+
+	d.liveMu.Lock()
+	defer d.liveMu.Unlock()
+	for ch := range d.live {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Package motion watches a framehub.FrameHub for frames, runs a lightweight
+// per-frame diff against a slowly-adapting background to decide whether
+// motion is occurring, and records each motion episode (plus a few seconds
+// of lead-in from a pre-roll ring buffer) to its own MP4 under an events
+// directory.
+package motion
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lsnow99/cam-server/framehub"
+	"github.com/lsnow99/cam-server/mp4enc"
+)
+
+// Config holds the tunable parameters of a Detector.
+type Config struct {
+	// MinOnSecs is how long motion must be continuously detected before an
+	// event recording starts.
+	MinOnSecs float64
+	// CooldownSecs is how long motion must be continuously absent before an
+	// in-progress event recording is closed.
+	CooldownSecs float64
+	// PreRollSecs is how many seconds of frames from before motion was
+	// detected are prepended to each event recording.
+	PreRollSecs int
+	// Threshold is the per-pixel grayscale difference (0-255) against the
+	// background frame required for a pixel to count as changed.
+	Threshold float64
+	// MinAreaFrac is the fraction, in [0,1], of the downscaled frame that
+	// the largest connected group of changed pixels must cover to count as
+	// motion.
+	MinAreaFrac float64
+}
+
+// Event describes a single completed motion recording.
+type Event struct {
+	ID        string
+	Start     time.Time
+	End       time.Time
+	Path      string
+	Thumbnail string
+}
+
+// Detector holds the state shared between the Run loop and the HTTP
+// handlers: the list of completed events and the set of live SSE
+// subscribers. The frame-by-frame analysis state (background model, ring
+// buffer, in-progress recording) lives entirely inside Run, since only that
+// goroutine ever touches it.
+type Detector struct {
+	cfg       Config
+	eventsDir string
+
+	mu     sync.Mutex
+	events []Event
+
+	liveMu sync.Mutex
+	live   map[chan liveEvent]struct{}
+}
+
+// NewDetector creates eventsDir if needed and returns a Detector ready to be
+// driven by Run.
+func NewDetector(eventsDir string, cfg Config) (*Detector, error) {
+	if err := ensureDir(eventsDir); err != nil {
+		return nil, err
+	}
+
+	return &Detector{
+		cfg:       cfg,
+		eventsDir: eventsDir,
+		live:      make(map[chan liveEvent]struct{}),
+	}, nil
+}
+
+// Run subscribes to hub and feeds every published frame through d's motion
+// detector, recording event clips to d's events directory while motion is
+// ongoing. Run blocks until ctx is canceled.
+func Run(ctx context.Context, errCh chan error, wg *sync.WaitGroup, hub *framehub.FrameHub, d *Detector) {
+	// This is guaranteed to run as the last thing before this function returns
+	defer wg.Done()
+
+	log.Println("Starting motion detector")
+
+	sub := hub.Subscribe(ctx)
+	defer sub.Close()
+
+	ring := newRingBuffer(time.Duration(d.cfg.PreRollSecs) * time.Second)
+	minOn := time.Duration(d.cfg.MinOnSecs * float64(time.Second))
+	cooldown := time.Duration(d.cfg.CooldownSecs * float64(time.Second))
+
+	var bg *background
+	var rec *eventRecording
+	var motionStartedAt, lastMotionAt time.Time
+
+	for frame := range sub.Frames() {
+		jpeg := frame.Data.Bytes()
+		ring.push(jpeg, frame.Time)
+
+		gray, err := decodeGray(jpeg)
+		if err != nil {
+			log.Printf("motion: decoding frame: %v", err)
+			continue
+		}
+
+		if bg == nil {
+			// First frame just seeds the background; there's nothing to
+			// compare it against yet.
+			bg = newBackground(gray)
+			continue
+		}
+
+		raw := bg.update(gray, d.cfg.Threshold, d.cfg.MinAreaFrac)
+
+		if raw {
+			if motionStartedAt.IsZero() {
+				motionStartedAt = frame.Time
+			}
+			lastMotionAt = frame.Time
+		} else {
+			motionStartedAt = time.Time{}
+		}
+
+		switch {
+		case rec == nil && raw && !motionStartedAt.IsZero() && frame.Time.Sub(motionStartedAt) >= minOn:
+			rec, err = d.startEvent(frame.Time, ring)
+			if err != nil {
+				errCh <- fmt.Errorf("starting motion event: %w", err)
+				continue
+			}
+			d.broadcastLive("motion_start", frame.Time)
+
+		case rec != nil:
+			if err := rec.feed(jpeg); err != nil {
+				errCh <- fmt.Errorf("recording motion event: %w", err)
+			}
+			if !raw && frame.Time.Sub(lastMotionAt) >= cooldown {
+				d.finishEvent(rec, frame.Time)
+				d.broadcastLive("motion_end", frame.Time)
+				rec = nil
+			}
+		}
+	}
+
+	if rec != nil {
+		d.finishEvent(rec, time.Now())
+	}
+
+	log.Println("Stopping motion detector")
+}
+
+// startEvent begins a new recording, seeding it with ring's current
+// contents so the clip includes the lead-in to the motion that triggered it.
+func (d *Detector) startEvent(start time.Time, ring *ringBuffer) (*eventRecording, error) {
+	id := strconv.FormatInt(start.Unix(), 10)
+	outPath := path.Join(d.eventsDir, id+".mp4")
+	thumbPath := path.Join(d.eventsDir, id+".jpg")
+
+	enc, err := mp4enc.New(outPath)
+	if err != nil {
+		return nil, err
+	}
+
+	preroll := ring.snapshot()
+	for i, f := range preroll {
+		if i == 0 {
+			if err := writeThumbnail(thumbPath, f.jpeg); err != nil {
+				log.Printf("motion: writing thumbnail %s: %v", thumbPath, err)
+				thumbPath = ""
+			}
+		}
+		if err := enc.WriteFrame(f.jpeg); err != nil {
+			enc.Close()
+			return nil, err
+		}
+	}
+	if len(preroll) == 0 {
+		thumbPath = ""
+	}
+
+	log.Printf("Motion started, recording %s", outPath)
+
+	return &eventRecording{
+		id:        id,
+		start:     start,
+		path:      outPath,
+		thumbnail: thumbPath,
+		enc:       enc,
+	}, nil
+}
+
+// finishEvent closes rec's encoder and appends it to d's event list.
+func (d *Detector) finishEvent(rec *eventRecording, end time.Time) {
+	if err := rec.enc.Close(); err != nil {
+		log.Printf("motion: closing event %s: %v", rec.path, err)
+	}
+
+	d.mu.Lock()
+	d.events = append(d.events, Event{
+		ID:        rec.id,
+		Start:     rec.start,
+		End:       end,
+		Path:      rec.path,
+		Thumbnail: rec.thumbnail,
+	})
+	d.mu.Unlock()
+
+	log.Printf("Motion ended, closed %s", rec.path)
+}
+
+// Events returns every completed event recorded so far, oldest first.
+func (d *Detector) Events() []Event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	events := make([]Event, len(d.events))
+	copy(events, d.events)
+	return events
+}
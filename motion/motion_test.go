@@ -0,0 +1,118 @@
+package motion
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"testing"
+	"time"
+)
+
+// makeFrame renders a 320x240 frame filled with bg, with a rect-sized block
+// of fg drawn at (rx, ry), and encodes it as a JPEG. These stand in for
+// recorded camera fixture frames: a static "empty room" background and
+// frames with a moving block simulate a subject passing through.
+func makeFrame(t *testing.T, bg, fg color.Gray, rx, ry, rsize int) []byte {
+	t.Helper()
+
+	img := image.NewGray(image.Rect(0, 0, 320, 240))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+	if rsize > 0 {
+		rect := image.Rect(rx, ry, rx+rsize, ry+rsize)
+		draw.Draw(img, rect, &image.Uniform{C: fg}, image.Point{}, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("encoding fixture frame: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestBackgroundUpdateFlagsNoMotionOnStaticScene(t *testing.T) {
+	empty := makeFrame(t, color.Gray{Y: 40}, color.Gray{}, 0, 0, 0)
+
+	gray, err := decodeGray(empty)
+	if err != nil {
+		t.Fatalf("decodeGray: %v", err)
+	}
+	bg := newBackground(gray)
+
+	for i := 0; i < 5; i++ {
+		gray, err := decodeGray(empty)
+		if err != nil {
+			t.Fatalf("decodeGray: %v", err)
+		}
+		if raw := bg.update(gray, 25, 0.02); raw {
+			t.Fatalf("iteration %d: got motion on an unchanging scene", i)
+		}
+	}
+}
+
+func TestBackgroundUpdateFlagsMotionOnLargeChange(t *testing.T) {
+	empty := makeFrame(t, color.Gray{Y: 40}, color.Gray{}, 0, 0, 0)
+	withSubject := makeFrame(t, color.Gray{Y: 40}, color.Gray{Y: 220}, 60, 60, 80)
+
+	gray, err := decodeGray(empty)
+	if err != nil {
+		t.Fatalf("decodeGray: %v", err)
+	}
+	bg := newBackground(gray)
+
+	gray, err = decodeGray(withSubject)
+	if err != nil {
+		t.Fatalf("decodeGray: %v", err)
+	}
+	if raw := bg.update(gray, 25, 0.02); !raw {
+		t.Fatal("expected motion to be detected when a large bright block appears")
+	}
+}
+
+func TestBackgroundUpdateIgnoresScatteredNoise(t *testing.T) {
+	// Scatter isolated 1-pixel-equivalent changes across the downscaled
+	// grid rather than one contiguous blob; none should individually form
+	// a connected component large enough to count as motion.
+	noisy := make([]bool, downscaleWidth*downscaleHeight)
+	for i := 0; i < len(noisy); i += 7 {
+		noisy[i] = true
+	}
+	if frac := largestComponentFrac(noisy, downscaleWidth, downscaleHeight); frac >= 0.02 {
+		t.Fatalf("got connected fraction %.4f for scattered noise, want < 0.02", frac)
+	}
+}
+
+func TestRingBufferDropsFramesOutsideWindow(t *testing.T) {
+	r := newRingBuffer(2 * time.Second)
+
+	base := time.Unix(0, 0)
+	r.push([]byte("t0"), base)
+	r.push([]byte("t1"), base.Add(time.Second))
+	r.push([]byte("t3"), base.Add(3*time.Second))
+
+	got := r.snapshot()
+	if len(got) != 2 {
+		t.Fatalf("got %d frames, want 2: %+v", len(got), got)
+	}
+	if string(got[0].jpeg) != "t1" || string(got[1].jpeg) != "t3" {
+		t.Fatalf("unexpected frames after window trim: %+v", got)
+	}
+}
+
+func TestLargestComponentFracFindsSingleBlob(t *testing.T) {
+	w, h := 10, 10
+	mask := make([]bool, w*h)
+	// 3x3 solid block somewhere in the middle.
+	for y := 3; y < 6; y++ {
+		for x := 3; x < 6; x++ {
+			mask[y*w+x] = true
+		}
+	}
+
+	frac := largestComponentFrac(mask, w, h)
+	want := 9.0 / 100.0
+	if frac != want {
+		t.Fatalf("got fraction %.4f, want %.4f", frac, want)
+	}
+}
@@ -0,0 +1,21 @@
+package motion
+
+import (
+	"time"
+
+	"github.com/lsnow99/cam-server/mp4enc"
+)
+
+// eventRecording is an in-progress motion event: an encoder plus the
+// metadata that will become an Event once it's closed.
+type eventRecording struct {
+	id        string
+	start     time.Time
+	path      string
+	thumbnail string
+	enc       *mp4enc.Encoder
+}
+
+func (rec *eventRecording) feed(jpeg []byte) error {
+	return rec.enc.WriteFrame(jpeg)
+}
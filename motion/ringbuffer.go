@@ -0,0 +1,42 @@
+package motion
+
+import "time"
+
+// ringFrame is a single pre-roll frame held by a ringBuffer.
+type ringFrame struct {
+	jpeg []byte
+	t    time.Time
+}
+
+// ringBuffer holds the last window worth of frames, oldest first, so a new
+// event recording can be seeded with a few seconds of lead-in from before
+// motion was detected. Frames aren't copied on push: the JPEG bytes
+// published by the frame hub are never reused by the publisher, so it's
+// safe to hold onto the slice directly.
+type ringBuffer struct {
+	window time.Duration
+	frames []ringFrame
+}
+
+func newRingBuffer(window time.Duration) *ringBuffer {
+	return &ringBuffer{window: window}
+}
+
+// push appends a frame and drops everything older than window relative to t.
+func (r *ringBuffer) push(jpeg []byte, t time.Time) {
+	r.frames = append(r.frames, ringFrame{jpeg: jpeg, t: t})
+
+	cutoff := t.Add(-r.window)
+	i := 0
+	for i < len(r.frames) && r.frames[i].t.Before(cutoff) {
+		i++
+	}
+	r.frames = r.frames[i:]
+}
+
+// snapshot returns the frames currently held, oldest first.
+func (r *ringBuffer) snapshot() []ringFrame {
+	out := make([]ringFrame, len(r.frames))
+	copy(out, r.frames)
+	return out
+}
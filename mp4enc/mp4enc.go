@@ -0,0 +1,111 @@
+// Package mp4enc runs a single long-lived ffmpeg process that ingests JPEG
+// stills over stdin and emits a fragmented MP4 over stdout, appending the
+// encoded bytes to a growing file as they arrive. Keeping one ffmpeg
+// process alive for the life of a recording avoids re-encoding the whole
+// archive of stills from scratch on every new frame; it's shared by the
+// root package's timelapse controller and the motion package's event
+// recorder, which both record JPEG streams to MP4 this way.
+package mp4enc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// Encoder owns a single long-lived ffmpeg process writing to one output
+// file. It's safe for concurrent use.
+type Encoder struct {
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	outFile *os.File
+	copyErr chan error
+}
+
+// New starts an ffmpeg process that writes a fresh fragmented-MP4 file to
+// outputPath.
+func New(outputPath string) (*Encoder, error) {
+	outFile, err := os.Create(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s: %w", outputPath, err)
+	}
+
+	cmd := exec.Command("ffmpeg",
+		"-f", "image2pipe",
+		"-framerate", "30",
+		"-i", "-",
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-movflags", "+frag_keyframe+empty_moov",
+		"-f", "mp4",
+		"pipe:1",
+	)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		outFile.Close()
+		return nil, fmt.Errorf("opening ffmpeg stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		outFile.Close()
+		return nil, fmt.Errorf("opening ffmpeg stdout: %w", err)
+	}
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		outFile.Close()
+		return nil, fmt.Errorf("starting ffmpeg: %w", err)
+	}
+
+	e := &Encoder{
+		cmd:     cmd,
+		stdin:   stdin,
+		outFile: outFile,
+		copyErr: make(chan error, 1),
+	}
+
+	// ffmpeg writes encoded MP4 bytes to stdout continuously as it receives
+	// stills on stdin; copy them straight through to the output file as
+	// they arrive rather than buffering the whole recording in memory.
+	go func() {
+		_, err := io.Copy(e.outFile, stdout)
+		e.copyErr <- err
+	}()
+
+	return e, nil
+}
+
+// WriteFrame feeds a single JPEG still into the encoder.
+func (e *Encoder) WriteFrame(jpeg []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := e.stdin.Write(jpeg)
+	return err
+}
+
+// Close stops ffmpeg and waits for its remaining output to be flushed to
+// the output file.
+func (e *Encoder) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// cmd.Wait closes the child's stdout pipe, so it must not run until the
+	// io.Copy goroutine has finished draining it; waiting first would race
+	// that goroutine's Read against Wait's Close and could truncate the
+	// trailing bytes ffmpeg flushes after stdin closes.
+	stdinErr := e.stdin.Close()
+	copyErr := <-e.copyErr
+	cmdErr := e.cmd.Wait()
+	fileErr := e.outFile.Close()
+
+	for _, err := range []error{stdinErr, cmdErr, copyErr, fileErr} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package mp4enc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// makeJPEG renders a tiny solid-color JPEG, standing in for a camera still.
+func makeJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewGray(image.Rect(0, 0, 32, 24))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("encoding fixture frame: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// assertCompleteMP4 walks path's top-level ISO BMFF boxes and fails if any
+// box's declared size runs past EOF, which is what Close's stdin.Close →
+// cmd.Wait → <-copyErr ordering bug truncated the output file into.
+func assertCompleteMP4(t *testing.T, path string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if len(data) == 0 {
+		t.Fatal("output file is empty")
+	}
+
+	pos := 0
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			t.Fatalf("truncated box header at offset %d (%d bytes remain)", pos, len(data)-pos)
+		}
+		size := binary.BigEndian.Uint32(data[pos : pos+4])
+		if size < 8 {
+			t.Fatalf("invalid box size %d at offset %d", size, pos)
+		}
+		if pos+int(size) > len(data) {
+			t.Fatalf("box at offset %d declares size %d but only %d bytes remain: file truncated", pos, size, len(data)-pos)
+		}
+		pos += int(size)
+	}
+}
+
+func TestEncoderProducesCompleteFile(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+
+	outPath := filepath.Join(t.TempDir(), "out.mp4")
+	enc, err := New(outPath)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	frame := makeJPEG(t)
+	for i := 0; i < 5; i++ {
+		if err := enc.WriteFrame(frame); err != nil {
+			t.Fatalf("WriteFrame: %v", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	assertCompleteMP4(t, outPath)
+}
@@ -1,21 +1,23 @@
 package main
 
 import (
-	"bytes"
 	"context"
+	"fmt"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
-	"path"
 	"strconv"
+	"strings"
 	"sync"
 	"text/template"
 	"time"
+
+	"github.com/lsnow99/cam-server/framehub"
 )
 
 type FrameClient struct {
-	li chan *bytes.Buffer
+	hub *framehub.FrameHub
 }
 
 type route struct {
@@ -54,15 +56,15 @@ func (rt *RouteTable) HandleRoot(w http.ResponseWriter, r *http.Request) {
 
 // HandleStream returns a stream of jpeg frames
 func (fc *FrameClient) HandleStream(w http.ResponseWriter, r *http.Request) {
-	// Remove stale image
-	<-fc.li
+	sub := fc.hub.Subscribe(r.Context())
+	defer sub.Close()
+
 	const boundary = `frame`
 	w.Header().Set("Content-Type", `multipart/x-mixed-replace;boundary=`+boundary)
 	multipartWriter := multipart.NewWriter(w)
 	multipartWriter.SetBoundary(boundary)
-	for {
-		img := <-fc.li
-		image := img.Bytes()
+	for frame := range sub.Frames() {
+		image := frame.Data.Bytes()
 		iw, err := multipartWriter.CreatePart(textproto.MIMEHeader{
 			"Content-type":   []string{"image/jpeg"},
 			"Content-length": []string{strconv.Itoa(len(image))},
@@ -81,31 +83,147 @@ func (fc *FrameClient) HandleStream(w http.ResponseWriter, r *http.Request) {
 
 // HandleSnapshot returns a single jpeg frame
 func (fc *FrameClient) HandleSnapshot(w http.ResponseWriter, r *http.Request) {
-	// Remove stale image
-	<-fc.li
+	sub := fc.hub.Subscribe(r.Context())
+	defer sub.Close()
 
-	img := <-fc.li
+	frame, ok := <-sub.Frames()
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "image/jpeg")
 
-	if _, err := w.Write(img.Bytes()); err != nil {
+	if _, err := w.Write(frame.Data.Bytes()); err != nil {
 		log.Println(err)
 		return
 	}
 }
 
+// HandleCam dispatches /cam/{name}/{snap,stream,timelapse,timelapse/rotate,
+// events,events/live,events/{id}.mp4} requests to the named camera's
+// FrameHub/TimelapseController/Detector, looked up fresh on every request so
+// a SIGHUP config reload that starts, stops, or replaces a camera takes
+// effect immediately without re-registering routes.
+func (m *CameraManager) HandleCam(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/cam/")
+	name, action, ok := strings.Cut(rest, "/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	rt, ok := m.Get(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown camera %q", name), http.StatusNotFound)
+		return
+	}
+
+	dispatchCamAction(rt, action, w, r)
+}
+
+// handleLegacyAlias serves action against whatever camera is first in
+// config order, so the original single-camera /snap, /stream, and
+// /timelapse routes keep working once a config defines multiple cameras.
+func handleLegacyAlias(m *CameraManager, action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := m.DefaultName()
+		if name == "" {
+			http.Error(w, "no cameras configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		rt, ok := m.Get(name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("default camera %q is not running", name), http.StatusServiceUnavailable)
+			return
+		}
+
+		dispatchCamAction(rt, action, w, r)
+	}
+}
+
+// handleLegacyEventFile serves /events/{id}.mp4 against the default camera,
+// mirroring the other legacy aliases. It's registered separately from
+// handleLegacyAlias because the event id is part of the path rather than a
+// fixed action string.
+func handleLegacyEventFile(m *CameraManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := m.DefaultName()
+		if name == "" {
+			http.Error(w, "no cameras configured", http.StatusServiceUnavailable)
+			return
+		}
+
+		rt, ok := m.Get(name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("default camera %q is not running", name), http.StatusServiceUnavailable)
+			return
+		}
+
+		dispatchCamAction(rt, strings.TrimPrefix(r.URL.Path, "/"), w, r)
+	}
+}
+
+func dispatchCamAction(rt *cameraRuntime, action string, w http.ResponseWriter, r *http.Request) {
+	fc := &FrameClient{hub: rt.hub}
+
+	switch {
+	case action == "snap":
+		fc.HandleSnapshot(w, r)
+	case action == "stream":
+		fc.HandleStream(w, r)
+	case action == "timelapse":
+		if rt.tc == nil {
+			http.Error(w, fmt.Sprintf("camera %q has timelapse disabled", rt.cfg.Name), http.StatusNotFound)
+			return
+		}
+		rt.tc.HandleTimelapse(w, r)
+	case action == "timelapse/rotate":
+		if rt.tc == nil {
+			http.Error(w, fmt.Sprintf("camera %q has timelapse disabled", rt.cfg.Name), http.StatusNotFound)
+			return
+		}
+		rt.tc.HandleRotate(w, r)
+	case action == "events":
+		if rt.motion == nil {
+			http.Error(w, fmt.Sprintf("camera %q has motion detection disabled", rt.cfg.Name), http.StatusNotFound)
+			return
+		}
+		rt.motion.HandleList(w, r)
+	case action == "events/live":
+		if rt.motion == nil {
+			http.Error(w, fmt.Sprintf("camera %q has motion detection disabled", rt.cfg.Name), http.StatusNotFound)
+			return
+		}
+		rt.motion.HandleLive(w, r)
+	case strings.HasPrefix(action, "events/") && strings.HasSuffix(action, ".mp4"):
+		if rt.motion == nil {
+			http.Error(w, fmt.Sprintf("camera %q has motion detection disabled", rt.cfg.Name), http.StatusNotFound)
+			return
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(action, "events/"), ".mp4")
+		rt.motion.HandleEventFile(w, r, id)
+	case strings.HasPrefix(action, "events/") && strings.HasSuffix(action, ".jpg"):
+		if rt.motion == nil {
+			http.Error(w, fmt.Sprintf("camera %q has motion detection disabled", rt.cfg.Name), http.StatusNotFound)
+			return
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(action, "events/"), ".jpg")
+		rt.motion.HandleEventThumbnail(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
 // ServeHttp start the http server
-func ServeHttp(ctx context.Context, errCh chan error, wg *sync.WaitGroup, port string, li chan *bytes.Buffer) {
+func ServeHttp(ctx context.Context, errCh chan error, wg *sync.WaitGroup, port string, manager *CameraManager) {
 	// This is guaranteed to run as the last thing before this function returns
 	defer wg.Done()
 
 	log.Printf("Starting http server on port %s", port)
 
-	fc := FrameClient{
-		li: li,
-	}
-
 	/*
 		Register some routes with our global routes table. This is just a neat way to
 		update our index.html without modifying the template, so it generates the
@@ -117,20 +235,83 @@ func ServeHttp(ctx context.Context, errCh chan error, wg *sync.WaitGroup, port s
 		Routes: []route{
 			{
 				Path:        "/snap",
-				Description: "view a still image of the camera",
-				handler:     fc.HandleSnapshot,
+				Description: "view a still image of the default camera",
+				handler:     handleLegacyAlias(manager, "snap"),
 			},
 			{
 				Path:        "/stream",
-				Description: "view a livestream of the camera",
-				handler:     fc.HandleStream,
+				Description: "view a livestream of the default camera",
+				handler:     handleLegacyAlias(manager, "stream"),
 			},
 			{
 				Path:        "/timelapse",
-				Description: "view a timelapse video",
-				handler: func(w http.ResponseWriter, r *http.Request) {
-					http.ServeFile(w, r, path.Join(TimelapseOutputDir, "timelapse.mp4"))
-				},
+				Description: "view a timelapse video of the default camera",
+				handler:     handleLegacyAlias(manager, "timelapse"),
+			},
+			{
+				Path:        "/timelapse/rotate",
+				Description: "POST to close the default camera's current timelapse segment and start a new one",
+				handler:     handleLegacyAlias(manager, "timelapse/rotate"),
+			},
+			{
+				Path:        "/events",
+				Description: "list recorded motion events for the default camera",
+				handler:     handleLegacyAlias(manager, "events"),
+			},
+			{
+				Path:        "/events/{id}.mp4",
+				Description: "download a recorded motion event video for the default camera",
+				handler:     handleLegacyEventFile(manager),
+			},
+			{
+				Path:        "/events/{id}.jpg",
+				Description: "view a recorded motion event's thumbnail for the default camera",
+				handler:     handleLegacyEventFile(manager),
+			},
+			{
+				Path:        "/events/live",
+				Description: "server-sent events stream of motion start/end notifications for the default camera",
+				handler:     handleLegacyAlias(manager, "events/live"),
+			},
+			{
+				Path:        "/cam/{name}/snap",
+				Description: "view a still image of camera {name}",
+				handler:     manager.HandleCam,
+			},
+			{
+				Path:        "/cam/{name}/stream",
+				Description: "view a livestream of camera {name}",
+				handler:     manager.HandleCam,
+			},
+			{
+				Path:        "/cam/{name}/timelapse",
+				Description: "view a timelapse video of camera {name}",
+				handler:     manager.HandleCam,
+			},
+			{
+				Path:        "/cam/{name}/timelapse/rotate",
+				Description: "POST to close camera {name}'s current timelapse segment and start a new one",
+				handler:     manager.HandleCam,
+			},
+			{
+				Path:        "/cam/{name}/events",
+				Description: "list recorded motion events for camera {name}",
+				handler:     manager.HandleCam,
+			},
+			{
+				Path:        "/cam/{name}/events/{id}.mp4",
+				Description: "download a recorded motion event video for camera {name}",
+				handler:     manager.HandleCam,
+			},
+			{
+				Path:        "/cam/{name}/events/{id}.jpg",
+				Description: "view a recorded motion event's thumbnail for camera {name}",
+				handler:     manager.HandleCam,
+			},
+			{
+				Path:        "/cam/{name}/events/live",
+				Description: "server-sent events stream of motion start/end notifications for camera {name}",
+				handler:     manager.HandleCam,
 			},
 		},
 	}
@@ -144,7 +325,16 @@ func ServeHttp(ctx context.Context, errCh chan error, wg *sync.WaitGroup, port s
 	*/
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", rt.HandleRoot)
+	mux.HandleFunc("/cam/", manager.HandleCam)
+	mux.HandleFunc("/events/", handleLegacyEventFile(manager))
 	for _, route := range rt.Routes {
+		if strings.HasPrefix(route.Path, "/cam/") || route.Path == "/events/{id}.mp4" || route.Path == "/events/{id}.jpg" {
+			// Already covered by the "/cam/" and "/events/" prefix
+			// registrations above; these entries only exist so the
+			// sitemap lists them (net/http's ServeMux has no way to
+			// register a path template like "{id}" as a literal pattern).
+			continue
+		}
 		mux.HandleFunc(route.Path, route.handler)
 	}
 
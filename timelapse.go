@@ -2,34 +2,149 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"context"
-	"io/ioutil"
+	"fmt"
 	"log"
-	"math"
+	"net/http"
 	"os"
-	"os/exec"
 	"path"
 	"strconv"
 	"sync"
 	"time"
+
+	"github.com/lsnow99/cam-server/framehub"
+	"github.com/lsnow99/cam-server/mp4enc"
 )
 
-// TimelapseWorker loop to take timelapse snapshots and stitch together the timelapse video
-func TimelapseWorker(ctx context.Context, errCh chan error, wg *sync.WaitGroup, li chan *bytes.Buffer) {
+// TimelapseController owns the active timelapse encoder and serializes
+// access to it between the TimelapseWorker goroutine (which feeds it
+// frames) and the HTTP handlers (which serve the growing segment and
+// handle rotate requests).
+type TimelapseController struct {
+	mu         sync.Mutex
+	enc        *mp4enc.Encoder
+	encPath    string
+	outputDir  string
+	keepStills bool
+}
+
+// NewTimelapseController creates outputDir if needed and starts the first
+// timelapse segment. When keepStills is false, the individual JPEG stills
+// are only fed to the encoder and never written to disk.
+func NewTimelapseController(outputDir string, keepStills bool) (*TimelapseController, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	segPath := path.Join(outputDir, "timelapse.mp4")
+	enc, err := mp4enc.New(segPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TimelapseController{enc: enc, encPath: segPath, outputDir: outputDir, keepStills: keepStills}, nil
+}
+
+// currentPath returns the path of the segment currently being written.
+func (tc *TimelapseController) currentPath() string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.encPath
+}
+
+// HandleTimelapse serves the current timelapse segment with byte-range
+// support so browsers can seek a growing file instead of downloading it
+// from the start every time.
+func (tc *TimelapseController) HandleTimelapse(w http.ResponseWriter, r *http.Request) {
+	p := tc.currentPath()
+
+	f, err := os.Open(p)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeContent(w, r, path.Base(p), info.ModTime(), f)
+}
+
+// HandleRotate closes the current segment and starts a new one, so an
+// operator can cut the timelapse into manageable chunks without stopping
+// the server.
+func (tc *TimelapseController) HandleRotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := tc.rotate(); err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// rotate closes the in-progress segment and starts a fresh one named after
+// the current time.
+func (tc *TimelapseController) rotate() error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if err := tc.enc.Close(); err != nil {
+		return fmt.Errorf("closing timelapse segment %s: %w", tc.encPath, err)
+	}
+
+	nextPath := path.Join(tc.outputDir, strconv.FormatInt(time.Now().Unix(), 10)+"_timelapse.mp4")
+	enc, err := mp4enc.New(nextPath)
+	if err != nil {
+		return fmt.Errorf("starting new timelapse segment: %w", err)
+	}
+	tc.enc = enc
+	tc.encPath = nextPath
+
+	log.Printf("Rotated timelapse to new segment %s", nextPath)
+	return nil
+}
+
+// feedFrame writes jpeg to the currently active encoder, surviving a
+// rotate() racing concurrently with it.
+func (tc *TimelapseController) feedFrame(jpeg []byte) error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.enc.WriteFrame(jpeg)
+}
+
+// close stops the active encoder, used during graceful shutdown.
+func (tc *TimelapseController) close() error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.enc.Close()
+}
+
+// TimelapseWorker loop to take timelapse snapshots and incrementally feed
+// them into tc's long-lived ffmpeg encoder, instead of re-running ffmpeg
+// over the full archive of stills on every tick. intervalMins is the
+// number of minutes between stills.
+func TimelapseWorker(ctx context.Context, errCh chan error, wg *sync.WaitGroup, hub *framehub.FrameHub, tc *TimelapseController, intervalMins int) {
 	// This is guaranteed to run as the last thing before this function returns
 	defer wg.Done()
 
 	log.Println("Starting timelapse worker")
 
-	if _, err := os.Stat(TimelapseOutputDir); os.IsNotExist(err) {
-		if err := os.Mkdir(TimelapseOutputDir, 0755); err != nil {
-			errCh <- err
-			return
-		}
-	}
+	sub := hub.Subscribe(ctx)
+	defer sub.Close()
 
-	ticker := time.NewTicker(time.Minute * time.Duration(TimelapseIntervalMins))
+	ticker := time.NewTicker(time.Minute * time.Duration(intervalMins))
 
 FOR:
 	for {
@@ -38,61 +153,50 @@ FOR:
 			// Shut down the worker
 			break FOR
 		case <-ticker.C:
-			// Take snapshot & encode new timelapse video
-
-			// Remove stale image
-			<-li
-
-			// Get new image
-			img := <-li
-
-			now := time.Now()
-
-			snapFilename := strconv.FormatInt(now.Unix(), 10) + ".jpg"
-
-			f, err := os.Create(path.Join(TimelapseOutputDir, snapFilename))
-			if err != nil {
-				errCh <- err
+			frame, ok := <-sub.Frames()
+			if !ok {
 				break FOR
 			}
 
-			w := bufio.NewWriter(f)
-			b, err := ioutil.ReadAll(img)
-			if err != nil {
-				errCh <- err
-				break FOR
+			now := time.Now()
+			jpeg := frame.Data.Bytes()
+
+			if tc.keepStills {
+				stillPath := path.Join(tc.outputDir, strconv.FormatInt(now.Unix(), 10)+".jpg")
+				if err := writeStill(stillPath, jpeg); err != nil {
+					errCh <- err
+					break FOR
+				}
 			}
-			_, err = w.Write(b)
-			if err != nil {
+
+			if err := tc.feedFrame(jpeg); err != nil {
 				errCh <- err
 				break FOR
 			}
 
 			log.Printf("Recorded timelapse snapshot @ %v", now)
-
-			encodeStarted := time.Now()
-			vidFilename := path.Join(TimelapseOutputDir, "timelapse.mp4")
-			jpgGlob := path.Join(TimelapseOutputDir, "*.jpg")
-			cmd := exec.Command("sh", "-c", `ffmpeg -framerate 30 -pattern_type glob -i "`+jpgGlob+`" -y `+vidFilename)
-			_, err = cmd.CombinedOutput()
-			if err != nil {
-				log.Println(err)
-				return
-			}
-			encodeDur := time.Since(encodeStarted)
-			minStr := strconv.FormatFloat(math.Floor(encodeDur.Minutes()), 'f', 0, 64)
-			secStr := strconv.FormatFloat(math.Floor(encodeDur.Seconds()), 'f', 0, 64)
-			if encodeDur >= time.Duration(TimelapseIntervalMins)*time.Minute {
-				log.Printf("ERROR: timelapse encoding took %sm%ss, greater than the timelapse capture interval of %dm", minStr, secStr, TimelapseIntervalMins)
-			} else if encodeDur >= (time.Duration(TimelapseIntervalMins)*time.Minute)/2 {
-				log.Printf("WARN: timelapse encoding took %sm%ss, greater than half of the timelapse capture interval of %dm", minStr, secStr, TimelapseIntervalMins)
-			} else {
-				log.Printf("INFO: timelapse encoding took %sm%ss", minStr, secStr)
-			}
 		}
 	}
 
 	ticker.Stop()
 
+	if err := tc.close(); err != nil {
+		log.Printf("error closing timelapse encoder: %v", err)
+	}
+
 	log.Println("Stopping timelapse worker")
 }
+
+func writeStill(p string, jpeg []byte) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if _, err := w.Write(jpeg); err != nil {
+		return err
+	}
+	return w.Flush()
+}